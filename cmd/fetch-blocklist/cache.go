@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// 条件付き取得用のメタデータ。リストごとに <list>.txt.cache.json として
+// リスト本体の隣に保存し、次回は If-None-Match / If-Modified-Since を送る。
+// ローカルファイルは更新時刻で判定する。
+// 取得元ごとの解析済みドメインも <list>.txt.<source>.cache に保存し、
+// 一部の取得元だけが 304 を返したときはそれを使う（取得し直さない）。
+
+// errNotModified は取得元が前回から変わっていないことを表す（HTTP 304）
+var errNotModified = errors.New("not modified")
+
+// sourceMeta は取得元ごとの検証子
+type sourceMeta struct {
+	Location     string `json:"location"` // URL またはパス。変わったら検証子は使わない
+	Format       Format `json:"format"`   // パーサとその設定。変わったら検証子は使わない
+	Column       string `json:"column,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// newSourceMeta は src の検証子なしのメタデータ
+func newSourceMeta(src Source) sourceMeta {
+	return sourceMeta{Location: src.location(), Format: src.Format, Column: src.Column}
+}
+
+// listCache は 1つのリストを構成する取得元のメタデータ（キーは Source.Name）
+type listCache struct {
+	Sources map[string]sourceMeta `json:"sources"`
+}
+
+// cachePath はリストのメタデータファイルのパス
+func cachePath(outputPath string) string {
+	return outputPath + ".cache.json"
+}
+
+// loadCache はメタデータを読み込む。無い・壊れている場合は空（= 全件取得）。
+func loadCache(path string) listCache {
+	c := listCache{Sources: make(map[string]sourceMeta)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Sources == nil {
+		return listCache{Sources: make(map[string]sourceMeta)}
+	}
+	return c
+}
+
+// validator は src に使える前回の検証子を返す
+func (c listCache) validator(src Source) (sourceMeta, bool) {
+	m, ok := c.Sources[src.Name]
+	if !ok || (m.ETag == "" && m.LastModified == "") {
+		return sourceMeta{}, false
+	}
+	if want := newSourceMeta(src); m.Location != want.Location || m.Format != want.Format || m.Column != want.Column {
+		return sourceMeta{}, false
+	}
+	return m, true
+}
+
+// sameSources は前回のメタデータが sources とちょうど同じ取得元の組のものか
+func (c listCache) sameSources(sources []Source) bool {
+	if len(c.Sources) != len(sources) {
+		return false
+	}
+	for _, src := range sources {
+		if _, ok := c.validator(src); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// save はメタデータをアトミックに書き込む（途中で止まっても検証子を失わない）
+func (c listCache) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// sourceCachePath は取得元の解析済みドメインを保存するパス。
+// 名前は URL のこともあるので、ファイル名に使えるようにエスケープする。
+func sourceCachePath(outputPath, name string) string {
+	return outputPath + "." + url.QueryEscape(name) + ".cache"
+}
+
+// sourceCacheHeader は解析済みドメインのキャッシュの 1行目。
+// 取得時の検証子を記録し、cache.json と食い違うキャッシュは使わない。
+func sourceCacheHeader(meta sourceMeta) (string, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return "# " + string(data), nil
+}
+
+// saveSourceCache は取得元のドメイン（ソート済み）を検証子と一緒に保存する
+func saveSourceCache(outputPath, name string, meta sourceMeta, domains []string) error {
+	header, err := sourceCacheHeader(meta)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for _, d := range domains {
+		b.WriteString(d + "\n")
+	}
+	return writeFileAtomic(sourceCachePath(outputPath, name), []byte(b.String()), 0o644)
+}
+
+// loadSourceCache は meta と同じ検証子で保存された取得元のドメインを読み込む
+func loadSourceCache(outputPath, name string, meta sourceMeta) ([]string, error) {
+	path := sourceCachePath(outputPath, name)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	want, err := sourceCacheHeader(meta)
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() || sc.Text() != want {
+		return nil, fmt.Errorf("%s: recorded for other validators", path)
+	}
+	var out []string
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, sc.Err()
+}
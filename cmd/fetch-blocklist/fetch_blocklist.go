@@ -5,101 +5,226 @@
 //	go run ./cmd/fetch-blocklist
 //	# → data/blocklist.txt を自動生成（ドメインのみ／重複排除／ソート）
 //
+//	go run ./cmd/fetch-blocklist --sources blocklist-sources.yaml
+//	# → 設定ファイルの list ごとに data/<list>.txt を生成（phishing / malware / ads など）
+//
+//	go run ./cmd/fetch-blocklist --report
+//	# → 前の世代からの増減を表示し、data/<list>.txt.diff.json に保存
+//
 // 目的：
 //
-//	StevenBlack/hosts の "hosts" をダウンロードして、urwarden が扱いやすい
-//	「ドメイン単体のリスト」に整形して保存する。
-//	署名検証とチェックサム検証をサポート。
+//	hosts・ドメイン列・URL列・CSV・adblock 形式の取得元をダウンロードして、
+//	urwarden が扱いやすい「ドメイン単体のリスト」に整形して保存する。
+//	--sources 未指定時は StevenBlack/hosts の "hosts" だけを取得する。
+//	public_key を設定した取得元は minisign 署名を検証してから使う。
 //
 // ポイント：
-//   - hosts形式の「IP アドレス ドメイン」行から、右端のドメインだけ抽出
+//   - 書式ごとのパーサ（parsers.go）でドメインだけ抽出（URL はホスト部分）
 //   - コメント行(#)や空行をスキップ
-//   - 末尾のドットや大文字小文字のゆれを正規化
+//   - 末尾のドットや大文字小文字のゆれを正規化、IP アドレスは除外
 //   - 重複を排除してからアルファベット順で保存
-//   - 保存先は data/blocklist.txt（無ければ作成）
-//   - GPG署名とチェックサム検証をサポート
+//   - 保存先は data/<list>.txt（無ければ作成）
+//   - 取得元の minisign（Ed25519）署名を検証、出力にはチェックサムと GPG 署名を付ける
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
 )
 
-// 取得元（必要に応じて切り替え可能）
-var sources = []string{
-	// StevenBlack/hosts の最新 hosts（生テキスト）
-	"https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts",
-	// もし高速にしたい場合は gzip 版などに切替も可能（例）
-	// "https://someone.example/hosts.gz",
+// options はコマンドラインフラグ
+type options struct {
+	skipVerify bool
+	force      bool
+	backup     bool
+	keep       int     // 保持するバックアップの世代数
+	report     bool    // 前の世代との差分を表示して <list>.txt.diff.json に保存
+	maxRemoved float64 // これを超える割合（%）のエントリが消える更新は公開しない（0 で無効）
 }
 
-// 出力先
-const outputPath = "data/blocklist.txt"
-const checksumPath = "data/blocklist.txt.sha256"
-
 func main() {
+	// サブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "rollback" {
+		os.Exit(runRollback(os.Args[2:]))
+	}
+
 	var (
-		skipVerify = flag.Bool("skip-verify", false, "skip signature and checksum verification")
-		force      = flag.Bool("force", false, "force update even if no changes detected")
-		backup     = flag.Bool("backup", true, "create backup of existing blocklist")
+		sourcesPath = flag.String("sources", "", "sources config file (YAML); default: StevenBlack hosts into data/blocklist.txt")
+		skipVerify  = flag.Bool("skip-verify", false, "skip signature verification of sources with a public_key and GPG signing of the output")
+		force       = flag.Bool("force", false, "force update even if no changes detected")
+		backup      = flag.Bool("backup", true, "create backup of existing blocklist")
+		keep        = flag.Int("keep-backups", 5, "number of backup generations kept per list")
+		report      = flag.Bool("report", false, "print what changed since the previous generation and write <list>.txt.diff.json")
+		maxRemoved  = flag.Float64("max-removed", 50, "refuse to publish a list when more than this percentage of its entries would disappear (0 disables)")
 	)
 	flag.Parse()
 
-	if err := run(context.Background(), *skipVerify, *force, *backup); err != nil {
+	cfg, err := loadSources(*sourcesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fetch-blocklist error:", err)
+		os.Exit(1)
+	}
+	if *keep < 1 {
+		fmt.Fprintln(os.Stderr, "fetch-blocklist error: --keep-backups must be at least 1")
+		os.Exit(1)
+	}
+	if *maxRemoved < 0 || *maxRemoved > 100 {
+		fmt.Fprintln(os.Stderr, "fetch-blocklist error: --max-removed must be between 0 and 100")
+		os.Exit(1)
+	}
+	opts := options{
+		skipVerify: *skipVerify,
+		force:      *force,
+		backup:     *backup,
+		keep:       *keep,
+		report:     *report,
+		maxRemoved: *maxRemoved,
+	}
+	if err := run(context.Background(), cfg, opts); err != nil {
 		fmt.Fprintln(os.Stderr, "fetch-blocklist error:", err)
 		os.Exit(1)
 	}
-	fmt.Println("OK: wrote", outputPath)
 }
 
-func run(ctx context.Context, skipVerify, force, backup bool) error {
-	// 既存のファイルのチェックサムを計算
-	existingChecksum, err := calculateFileChecksum(outputPath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to calculate existing checksum: %w", err)
+// run はリストごとにすべてのソースを取得・解析し、1ファイルにまとめて保存する。
+// 1つでも取得に失敗したリストは書き換えない（エントリが消えるのを防ぐ）。
+func run(ctx context.Context, cfg *SourcesConfig, opts options) error {
+	client := newHTTPClient()
+
+	for _, list := range cfg.lists() {
+		if err := runList(ctx, client, cfg, list, opts); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// HTTPクライアント（タイムアウト・TLS設定付き）
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   15 * time.Second,
-				KeepAlive: 15 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       30 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
-		},
+// runList は 1つのリストを構成するソースを取得して保存する。
+// 前回の ETag / Last-Modified があれば条件付きで取得し、すべて 304 なら何もしない。
+func runList(ctx context.Context, client *http.Client, cfg *SourcesConfig, list string, opts options) error {
+	sources := cfg.sourcesOf(list)
+	outputPath := filepath.Join(cfg.OutputDir, list+".txt")
+
+	// 前回の公開が途中で止まっていたらチェックサムを書き直す（そうでなければ
+	// 戻さない：verify_checksums では読み込みを拒否されたままになる）
+	if err := ensureChecksum(outputPath); err != nil {
+		fmt.Printf("Warning: not restoring the checksum: %v\n", err)
 	}
 
+	// --force やリスト本体が無い場合は検証子を使わずに全件取得
+	cache := loadCache(cachePath(outputPath))
+	_, statErr := os.Stat(outputPath)
+	conditional := !opts.force && statErr == nil
+
 	// すべてのソースを順に取得して 1つの set に集約
 	set := make(map[string]struct{}, 100_000)
-
+	next := listCache{Sources: make(map[string]sourceMeta, len(sources))}
+	fetched := make(map[string][]string, len(sources)) // 今回ダウンロードした取得元のドメイン
+	var unchanged []Source
+	fetch := func(src Source, prev *sourceMeta) error {
+		if src.key != nil && opts.skipVerify {
+			fmt.Printf("Warning: %s: %s is used without signature verification (--skip-verify)\n", list, src.Name)
+		}
+		domains := make(map[string]struct{})
+		n, meta, err := fetchAndParse(ctx, client, src, prev, !opts.skipVerify, domains)
+		if errors.Is(err, errNotModified) {
+			fmt.Printf("%s: %s not modified\n", list, src.Name)
+			unchanged = append(unchanged, src)
+			next.Sources[src.Name] = *prev
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("fetch %s: %w", src.Name, err)
+		}
+		fmt.Printf("%s: %d domains from %s\n", list, n, src.Name)
+		next.Sources[src.Name] = meta
+		fetched[src.Name] = slices.Sorted(maps.Keys(domains))
+		maps.Copy(set, domains)
+		return nil
+	}
 	for _, src := range sources {
-		if err := fetchAndParse(ctx, client, src, set); err != nil {
-			return fmt.Errorf("fetch %s: %w", src, err)
+		var prev *sourceMeta
+		if m, ok := cache.validator(src); ok && conditional {
+			prev = &m
+		}
+		if err := fetch(src, prev); err != nil {
+			return err
+		}
+	}
+
+	// 取得元が外された・パーサの設定が変わった場合は、すべて 304 でも作り直す
+	if len(unchanged) == len(sources) && cache.sameSources(sources) {
+		fmt.Println("No changes upstream, skipping update:", outputPath)
+		return nil
+	}
+	// 一部のソースだけ 304 の場合は、前回保存した解析済みドメインを使う。
+	// キャッシュが無い・検証子が合わない場合だけ検証子なしで取得し直す。
+	for _, src := range unchanged {
+		cached, err := loadSourceCache(outputPath, src.Name, next.Sources[src.Name])
+		if err == nil {
+			fmt.Printf("%s: %d domains from %s (cached)\n", list, len(cached), src.Name)
+			for _, d := range cached {
+				set[d] = struct{}{}
+			}
+			continue
+		}
+		if err := fetch(src, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := publish(outputPath, set, sources, opts); err != nil {
+		return fmt.Errorf("%s: %w", outputPath, err)
+	}
+	// 解析済みドメインと検証子はリストの保存に成功してから記録する
+	// （失敗時は次回も全件取得）
+	for name, domains := range fetched {
+		if err := saveSourceCache(outputPath, name, next.Sources[name], domains); err != nil {
+			fmt.Printf("Warning: failed to cache domains of %s: %v\n", name, err)
+			delete(next.Sources, name)
+		}
+	}
+	if err := next.save(cachePath(outputPath)); err != nil {
+		fmt.Printf("Warning: failed to save fetch cache: %v\n", err)
+	}
+	// 設定から外れた取得元のキャッシュは削除
+	for name := range cache.Sources {
+		if _, ok := next.Sources[name]; !ok {
+			_ = os.Remove(sourceCachePath(outputPath, name))
 		}
 	}
+	return nil
+}
+
+// publish は set をソートして outputPath に保存する（チェックサム・署名付き）
+func publish(outputPath string, set map[string]struct{}, sources []Source, opts options) error {
+	checksumPath := outputPath + ".sha256"
+
+	// 既存のファイルのチェックサムを計算
+	existingChecksum, err := calculateFileChecksum(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to calculate existing checksum: %w", err)
+	}
 
 	// set → ソート済みスライスへ
 	domains := make([]string, 0, len(set))
@@ -109,20 +234,29 @@ func run(ctx context.Context, skipVerify, force, backup bool) error {
 	sort.Strings(domains)
 
 	// 新しいコンテンツのチェックサムを計算
-	newContent := generateBlocklistContent(domains)
+	newContent := generateBlocklistContent(domains, sources)
 	newChecksum := calculateChecksum(newContent)
 
-	// 変更がない場合はスキップ
-	if !force && existingChecksum != "" && existingChecksum == newChecksum {
-		fmt.Println("No changes detected, skipping update")
+	// 前の世代のドメイン（無ければ空）
+	previous, err := readDomains(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read previous list: %w", err)
+	}
+	slices.Sort(previous) // 手で編集されたリストでも比較できるように
+
+	// 変更がない場合はスキップ（ヘッダの生成日時は比較しない）
+	if !opts.force && existingChecksum != "" && slices.Equal(previous, domains) {
+		fmt.Println("No changes detected, skipping update:", outputPath)
 		return nil
 	}
 
-	// バックアップを作成
-	if backup && existingChecksum != "" {
-		if err := createBackup(outputPath); err != nil {
-			fmt.Printf("Warning: failed to create backup: %v\n", err)
-		}
+	// 差分の表示と、大量削除の安全装置（置き換える前に判定する）
+	diff := diffDomains(strings.TrimSuffix(filepath.Base(outputPath), ".txt"), outputPath, previous, domains)
+	if opts.report {
+		diff.print(os.Stdout)
+	}
+	if err := diff.check(opts.maxRemoved); err != nil {
+		return err
 	}
 
 	// 出力ディレクトリ作成
@@ -130,28 +264,94 @@ func run(ctx context.Context, skipVerify, force, backup bool) error {
 		return fmt.Errorf("mkdir: %w", err)
 	}
 
-	// ファイルを保存
-	if err := os.WriteFile(outputPath, newContent, 0644); err != nil {
-		return fmt.Errorf("write file: %w", err)
+	// 現在の世代を退避（失敗したら置き換えない：戻せない更新はしない）
+	if opts.backup && existingChecksum != "" {
+		backup, err := createBackup(outputPath, time.Now())
+		if err != nil {
+			return fmt.Errorf("create backup: %w", err)
+		}
+		fmt.Println("Backup:", backup)
 	}
 
-	// チェックサムファイルを保存
-	if err := os.WriteFile(checksumPath, []byte(newChecksum+"  "+filepath.Base(outputPath)+"\n"), 0644); err != nil {
+	// 古いチェックサムを消してからリスト本体、新しいチェックサムの順に置き換える。
+	// 途中で止まると .sha256 の無いリストが残り、verify_checksums では読み込みを
+	// 拒否されるが、次回の実行で ensureChecksum が印に残した新旧のチェックサムと
+	// 照らし合わせて書き直す。
+	oldSum, err := readChecksum(outputPath)
+	if err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+	newSum := checksumLine(outputPath, newChecksum)
+	if err := beginReplace(outputPath, newSum, oldSum); err != nil {
+		return fmt.Errorf("mark update: %w", err)
+	}
+	if err := os.Remove(checksumPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove outdated checksum: %w", err)
+	}
+	if err := writeFileAtomic(outputPath, newContent, 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := writeFileAtomic(checksumPath, []byte(newSum), 0o644); err != nil {
 		return fmt.Errorf("write checksum: %w", err)
 	}
+	if err := endReplace(outputPath); err != nil {
+		return fmt.Errorf("clear update mark: %w", err)
+	}
+	// 以前の minisign 署名は新しい内容と一致しないので残さない（バックアップ側には残る）
+	if err := os.Remove(outputPath + ".minisig"); err == nil {
+		fmt.Println("Removed outdated signature, re-sign the list:", outputPath+".minisig")
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("remove outdated signature: %w", err)
+	}
+
+	if opts.report {
+		data, err := diff.json()
+		if err == nil {
+			err = writeFileAtomic(diffPath(outputPath), data, 0o644)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to write report: %v\n", err)
+		}
+	}
+
+	if opts.backup {
+		if err := pruneBackups(outputPath, opts.keep); err != nil {
+			fmt.Printf("Warning: failed to remove old backups: %v\n", err)
+		}
+	}
 
-	// 署名検証（スキップしない場合）
-	if !skipVerify {
-		if err := verifyAndSign(outputPath, newChecksum); err != nil {
-			fmt.Printf("Warning: signature verification failed: %v\n", err)
-			fmt.Println("You can use --skip-verify to bypass signature verification")
+	// GPG 署名の作成と書き込み結果の確認（スキップしない場合）
+	if !opts.skipVerify {
+		if err := signAndCheck(outputPath, newChecksum); err != nil {
+			fmt.Printf("Warning: %v\n", err)
 		}
 	}
 
+	fmt.Println("OK: wrote", outputPath)
 	return nil
 }
 
-func generateBlocklistContent(domains []string) []byte {
+// newHTTPClient はタイムアウト・TLS設定付きの HTTP クライアントを返す
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   15 * time.Second,
+				KeepAlive: 15 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       30 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+	}
+}
+
+func generateBlocklistContent(domains []string, sources []Source) []byte {
 	var content strings.Builder
 
 	header := fmt.Sprintf(
@@ -160,7 +360,7 @@ func generateBlocklistContent(domains []string) []byte {
 	)
 	content.WriteString(header)
 	for _, s := range sources {
-		content.WriteString("#   " + s + "\n")
+		content.WriteString(fmt.Sprintf("#   %s (%s)\n", s.location(), s.Format))
 	}
 	content.WriteString("\n")
 
@@ -171,6 +371,21 @@ func generateBlocklistContent(domains []string) []byte {
 	return []byte(content.String())
 }
 
+// readDomains はリストファイルのドメイン行（コメント・空行以外）を返す
+func readDomains(path string) ([]string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var out []string
+	err = scanLines(f, func(line string) {
+		out = append(out, line)
+	})
+	return out, err
+}
+
 func calculateFileChecksum(filename string) (string, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -184,18 +399,16 @@ func calculateChecksum(data []byte) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func createBackup(filename string) error {
-	backupFile := filename + ".backup"
-	return os.Rename(filename, backupFile)
-}
-
-func verifyAndSign(filename, checksum string) error {
+// signAndCheck は出力に GPG 署名（.asc、gpg があれば）を付け、書き込んだファイルを
+// 読み戻してチェックサムと比べる。信頼できる鍵での検証ではない（それは取得元の
+// minisign 署名と urwarden 側の verify_checksums / list_public_keys が行う）。
+func signAndCheck(filename, checksum string) error {
 	// GPG署名の生成（オプション）
 	if err := generateSignature(filename); err != nil {
 		fmt.Printf("Warning: failed to generate signature: %v\n", err)
 	}
 
-	// チェックサムの検証
+	// 書き込んだ内容の確認
 	if err := verifyChecksum(filename, checksum); err != nil {
 		return fmt.Errorf("checksum verification failed: %w", err)
 	}
@@ -230,76 +443,164 @@ func verifyChecksum(filename, expectedChecksum string) error {
 	return nil
 }
 
-// fetchAndParse は URL から hosts 形式（またはプレーンなドメイン列）を取得し、
-// 見つけたドメインを set に追加する。
-func fetchAndParse(ctx context.Context, client *http.Client, url string, set map[string]struct{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// fetchAndParse は取得元を開き、src.Format のパーサで見つけたドメインを set に追加する。
+// 戻り値は取得元に含まれていた（正規化後の）ドメイン数と、次回用の検証子。
+// prev の検証子から変わっていなければ errNotModified を返す。
+// verify なら public_key 付きの取得元の署名を検証し、一致しなければエラー。
+func fetchAndParse(ctx context.Context, client *http.Client, src Source, prev *sourceMeta, verify bool, set map[string]struct{}) (int, sourceMeta, error) {
+	body, meta, err := openSource(ctx, client, src, prev, verify)
 	if err != nil {
-		return err
+		return 0, meta, err
 	}
-	req.Header.Set("User-Agent", "urwarden-fetch-blocklist/0.1")
+	defer func() { _ = body.Close() }()
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = resp.Body.Close() }()
+	seen := make(map[string]struct{})
+	err = parsers[src.Format](body, src, func(s string) {
+		if dom := normalizeDomain(s); dom != "" {
+			seen[dom] = struct{}{}
+			set[dom] = struct{}{}
+		}
+	})
+	return len(seen), meta, err
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
+// openSource は URL からのダウンロード、またはローカルファイルを開く。
+// prev があれば If-None-Match / If-Modified-Since を送り、304 は errNotModified。
+// ローカルファイルは更新時刻を Last-Modified として扱う。
+// gzip（Content-Encoding または拡張子 .gz）は解凍する。
+// verify かつ src に公開鍵があれば、配布されたファイルそのもの（.gz なら解凍前）の
+// 署名を検証する。
+func openSource(ctx context.Context, client *http.Client, src Source, prev *sourceMeta, verify bool) (io.ReadCloser, sourceMeta, error) {
+	var (
+		body io.ReadCloser
+		gz   bool
+	)
+	meta := newSourceMeta(src)
+	if src.Path != "" {
+		f, err := os.Open(filepath.Clean(src.Path))
+		if err != nil {
+			return nil, meta, err
+		}
+		if info, err := f.Stat(); err == nil {
+			meta.LastModified = info.ModTime().UTC().Format(time.RFC3339Nano)
+		}
+		if prev != nil && prev.LastModified == meta.LastModified {
+			_ = f.Close()
+			return nil, meta, errNotModified
+		}
+		body, gz = f, strings.HasSuffix(src.Path, ".gz")
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+		if err != nil {
+			return nil, meta, err
+		}
+		req.Header.Set("User-Agent", "urwarden-fetch-blocklist/0.1")
+		if prev != nil {
+			if prev.ETag != "" {
+				req.Header.Set("If-None-Match", prev.ETag)
+			}
+			if prev.LastModified != "" {
+				req.Header.Set("If-Modified-Since", prev.LastModified)
+			}
+		}
 
-	var r io.Reader = resp.Body
-	// もし Content-Encoding が gzip なら解凍（StevenBlackは通常プレーン）
-	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-		gr, gzErr := gzip.NewReader(resp.Body)
-		if gzErr != nil {
-			return gzErr
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, meta, err
+		}
+		if resp.StatusCode == http.StatusNotModified && prev != nil {
+			_ = resp.Body.Close()
+			return nil, *prev, errNotModified
 		}
-		defer func() { _ = gr.Close() }()
-		r = gr
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return nil, meta, fmt.Errorf("bad status: %s", resp.Status)
+		}
+		meta.ETag = resp.Header.Get("ETag")
+		meta.LastModified = resp.Header.Get("Last-Modified")
+		body = resp.Body
+		// 転送時の圧縮は署名の対象外なので先に解凍する
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			if body, err = gunzip(body); err != nil {
+				return nil, meta, err
+			}
+		}
+		gz = strings.HasSuffix(req.URL.Path, ".gz")
 	}
 
-	sc := bufio.NewScanner(r)
-	// 1行が長い場合に備えてバッファ拡張
-	const maxLine = 1024 * 1024
-	buf := make([]byte, 0, 64*1024)
-	sc.Buffer(buf, maxLine)
-
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue // コメント・空行をスキップ
+	if verify && src.key != nil {
+		var err error
+		if body, err = verifySource(ctx, client, src, body); err != nil {
+			return nil, meta, err
 		}
+	}
+	if !gz {
+		return body, meta, nil
+	}
+	body, err := gunzip(body)
+	return body, meta, err
+}
 
-		// 例）"0.0.0.0 bad.example.com" / "127.0.0.1 foo.bar" / "malicious.test"
-		fields := strings.Fields(line)
-		if len(fields) == 0 {
-			continue
-		}
+// gunzip は body を解凍するストリームを返す（失敗時は body を閉じる）
+func gunzip(body io.ReadCloser) (io.ReadCloser, error) {
+	gr, err := gzip.NewReader(body)
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+	return gzipReadCloser{gr, body}, nil
+}
 
-		var dom string
-		switch len(fields) {
-		case 1:
-			// ドメインだけが書かれている形式
-			dom = fields[0]
-		default:
-			// hosts形式（"IP ドメイン" ...）の想定：右端をドメインとみなす
-			dom = fields[len(fields)-1]
-			// 先頭のフィールドがIPっぽいか軽くチェック（IP以外でも右端優先でOK）
-			_ = net.ParseIP(fields[0]) // 使わないが念のための可読性
-		}
+// maxSignatureSize は署名ファイルの上限（minisign の .minisig は 1KB 未満）
+const maxSignatureSize = 64 * 1024
 
-		dom = normalizeDomain(dom)
-		if dom == "" {
-			continue
-		}
-		set[dom] = struct{}{}
+// verifySource は body を読み切って src.signature() の署名を src.key で検証し、
+// 検証済みの内容を返す。body は閉じる。
+func verifySource(ctx context.Context, client *http.Client, src Source, body io.ReadCloser) (io.ReadCloser, error) {
+	data, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
 	}
-	if err := sc.Err(); err != nil {
-		return err
+	sig, err := readSignature(ctx, client, src.signature())
+	if err != nil {
+		return nil, fmt.Errorf("signature %s: %w", src.signature(), err)
 	}
-	return nil
+	if err := minisign.Verify([]minisign.PublicKey{*src.key}, data, sig); err != nil {
+		return nil, fmt.Errorf("signature %s: %w", src.signature(), err)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// readSignature は署名ファイルを URL またはローカルファイルから読む
+func readSignature(ctx context.Context, client *http.Client, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
+		return os.ReadFile(filepath.Clean(location))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "urwarden-fetch-blocklist/0.1")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
+
+// gzipReadCloser は解凍ストリームと元のストリームの両方を閉じる
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (g gzipReadCloser) Close() error {
+	return errors.Join(g.Reader.Close(), g.body.Close())
 }
 
 // normalizeDomain はドメインの簡易正規化（小文字・末尾のドット除去・前後空白除去）。
@@ -312,6 +613,10 @@ func normalizeDomain(s string) string {
 		s = s[:i]
 	}
 	s = strings.ToLower(strings.Trim(s, "."))
+	// ドメインのみのリストなので IP アドレス（hosts の "0.0.0.0" など）は除外
+	if net.ParseIP(s) != nil {
+		return ""
+	}
 	// 最低限のバリデーション（空やワイルドカードのみは除外）
 	if s == "" || strings.ContainsAny(s, " /\\") {
 		return ""
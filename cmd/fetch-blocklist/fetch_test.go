@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
+)
+
+// feed is an upstream list served with ETag and Last-Modified validators
+type feed struct {
+	mu       sync.Mutex
+	body     string
+	version  int
+	modified time.Time
+	full     int // 200 responses
+	notMod   int // 304 responses
+}
+
+func (f *feed) set(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+	f.version++
+	f.modified = time.Date(2024, 1, 1, 0, f.version, 0, 0, time.UTC)
+}
+
+func (f *feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	etag := fmt.Sprintf(`"v%d"`, f.version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", f.modified.Format(http.TimeFormat))
+	if r.Header.Get("If-None-Match") == etag {
+		f.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	f.full++
+	_, _ = w.Write([]byte(f.body))
+}
+
+func (f *feed) counts() (full, notMod int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.full, f.notMod
+}
+
+func TestRun_ConditionalFetch(t *testing.T) {
+	phish, malware := &feed{}, &feed{}
+	phish.set("https://login.phish.test/\n")
+	malware.set("0.0.0.0 malware.test\n")
+	mux := http.NewServeMux()
+	mux.Handle("/phish.txt", phish)
+	mux.Handle("/hosts", malware)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	out := t.TempDir()
+	cfg := &SourcesConfig{OutputDir: out, Sources: []Source{
+		{Name: "phish", URL: ts.URL + "/phish.txt", Format: FormatURLs, List: "bad"},
+		{Name: "malware", URL: ts.URL + "/hosts", Format: FormatHosts, List: "bad"},
+	}}
+	opts := options{skipVerify: true}
+	list := filepath.Join(out, "bad.txt")
+	runOnce := func() {
+		t.Helper()
+		if err := run(context.Background(), cfg, opts); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+
+	// First run: no validators yet, full downloads
+	runOnce()
+	if full, notMod := phish.counts(); full != 1 || notMod != 0 {
+		t.Fatalf("first run: phish full=%d 304=%d", full, notMod)
+	}
+	cache := loadCache(cachePath(list))
+	if m := cache.Sources["phish"]; m.ETag != `"v1"` || m.LastModified == "" || m.Location != ts.URL+"/phish.txt" {
+		t.Fatalf("cache = %+v", cache)
+	}
+	before, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Second run: both sources answer 304 and the list is left alone
+	runOnce()
+	if full, notMod := phish.counts(); full != 1 || notMod != 1 {
+		t.Errorf("second run: phish full=%d 304=%d, want 1 and 1", full, notMod)
+	}
+	if full, notMod := malware.counts(); full != 1 || notMod != 1 {
+		t.Errorf("second run: malware full=%d 304=%d, want 1 and 1", full, notMod)
+	}
+	if after, _ := os.ReadFile(list); string(after) != string(before) {
+		t.Errorf("list rewritten although nothing changed")
+	}
+
+	// One source changes: the unchanged one answers 304 and its cached
+	// domains stay in the merged list
+	phish.set("https://login.phish.test/\nhttps://new.phish.test/\n")
+	runOnce()
+	got, err := readDomains(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"login.phish.test", "malware.test", "new.phish.test"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if full, notMod := malware.counts(); full != 1 || notMod != 2 {
+		t.Errorf("third run: malware full=%d 304=%d, want 1 and 2", full, notMod)
+	}
+	if m := loadCache(cachePath(list)).Sources["phish"]; m.ETag != `"v2"` {
+		t.Errorf("cache not updated: %+v", m)
+	}
+
+	// Without its domain cache the unchanged source is downloaded again
+	if err := os.Remove(sourceCachePath(list, "malware")); err != nil {
+		t.Fatal(err)
+	}
+	phish.set("https://login.phish.test/\n")
+	runOnce()
+	if got, _ := readDomains(list); fmt.Sprint(got) != "[login.phish.test malware.test]" {
+		t.Errorf("list = %v", got)
+	}
+	if full, notMod := malware.counts(); full != 2 || notMod != 3 {
+		t.Errorf("fourth run: malware full=%d 304=%d, want 2 and 3", full, notMod)
+	}
+	if _, err := os.Stat(sourceCachePath(list, "malware")); err != nil {
+		t.Errorf("domain cache not rebuilt: %v", err)
+	}
+
+	// --force ignores the validators
+	opts.force = true
+	runOnce()
+	if full, _ := phish.counts(); full != 4 {
+		t.Errorf("forced run: phish full=%d, want 4", full)
+	}
+}
+
+func TestRun_SourceRemoved(t *testing.T) {
+	phish, malware := &feed{}, &feed{}
+	phish.set("https://login.phish.test/\n")
+	malware.set("0.0.0.0 malware.test\n")
+	mux := http.NewServeMux()
+	mux.Handle("/phish.txt", phish)
+	mux.Handle("/hosts", malware)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	out := t.TempDir()
+	cfg := &SourcesConfig{OutputDir: out, Sources: []Source{
+		{Name: "phish", URL: ts.URL + "/phish.txt", Format: FormatURLs, List: "bad"},
+		{Name: "malware", URL: ts.URL + "/hosts", Format: FormatHosts, List: "bad"},
+	}}
+	if err := run(context.Background(), cfg, options{skipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remaining source answers 304, but the list must drop malware's domains
+	cfg.Sources = cfg.Sources[:1]
+	if err := run(context.Background(), cfg, options{skipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	list := filepath.Join(out, "bad.txt")
+	if got, _ := readDomains(list); fmt.Sprint(got) != "[login.phish.test]" {
+		t.Errorf("list = %v, want only the phish domains", got)
+	}
+	if full, notMod := phish.counts(); full != 1 || notMod != 1 {
+		t.Errorf("phish full=%d 304=%d, want 1 and 1", full, notMod)
+	}
+	if data, _ := os.ReadFile(list); strings.Contains(string(data), "/hosts") {
+		t.Errorf("header still names the removed source:\n%s", data)
+	}
+	if _, err := os.Stat(sourceCachePath(list, "malware")); !os.IsNotExist(err) {
+		t.Errorf("cache of the removed source left behind: %v", err)
+	}
+	if _, ok := loadCache(cachePath(list)).Sources["malware"]; ok {
+		t.Errorf("validators of the removed source kept")
+	}
+
+	// A changed parser setting also forces a rebuild, with a full download
+	cfg.Sources[0].Format = FormatAdblock
+	if err := run(context.Background(), cfg, options{skipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if full, _ := phish.counts(); full != 2 {
+		t.Errorf("phish full=%d after a format change, want 2", full)
+	}
+}
+
+func TestRun_ConditionalFetchMissingList(t *testing.T) {
+	f := &feed{}
+	f.set("bad.test\n")
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	out := t.TempDir()
+	cfg := &SourcesConfig{OutputDir: out, Sources: []Source{
+		{Name: "feed", URL: ts.URL, Format: FormatDomains, List: "blocklist"},
+	}}
+	if err := run(context.Background(), cfg, options{skipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	// Without the list its validators are useless: download again
+	if err := os.Remove(filepath.Join(out, "blocklist.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), cfg, options{skipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if full, notMod := f.counts(); full != 2 || notMod != 0 {
+		t.Errorf("full=%d 304=%d, want 2 and 0", full, notMod)
+	}
+	if _, err := os.Stat(filepath.Join(out, "blocklist.txt")); err != nil {
+		t.Errorf("list not rebuilt: %v", err)
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "bad.txt")
+	meta := sourceMeta{Location: "https://feed.example/list.txt", ETag: `"v1"`}
+	if err := saveSourceCache(list, meta.Location, meta, []string{"a.test", "b.test"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sourceCachePath(list, meta.Location), "bad.txt.https%3A%2F%2Ffeed.example%2Flist.txt.cache") {
+		t.Errorf("cache path = %s", sourceCachePath(list, meta.Location))
+	}
+	got, err := loadSourceCache(list, meta.Location, meta)
+	if err != nil || fmt.Sprint(got) != "[a.test b.test]" {
+		t.Errorf("loadSourceCache() = %v, %v", got, err)
+	}
+	// Domains recorded for other validators are not used
+	meta.ETag = `"v2"`
+	if _, err := loadSourceCache(list, meta.Location, meta); err == nil {
+		t.Error("loadSourceCache() accepted a cache for other validators")
+	}
+}
+
+func TestFetchAndParse_Signed(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := minisign.PublicKey{ID: minisign.KeyID{7}, Key: pub}
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	body := "bad.test\nevil.test\n"
+	var gzBody bytes.Buffer
+	zw := gzip.NewWriter(&gzBody)
+	_, _ = zw.Write([]byte(body))
+	_ = zw.Close()
+
+	files := map[string][]byte{
+		"/list.txt":            []byte(body),
+		"/list.txt.minisig":    minisign.Sign(priv, key.ID, []byte(body), "file:list.txt"),
+		"/list.txt.gz":         gzBody.Bytes(),
+		"/list.txt.gz.minisig": minisign.Sign(priv, key.ID, gzBody.Bytes(), "file:list.txt.gz"),
+		"/forged.txt":          []byte(body + "good.example.com\n"),
+		"/forged.txt.minisig":  minisign.Sign(priv, key.ID, []byte(body), "file:list.txt"),
+		"/other.txt":           []byte(body),
+		"/other.txt.minisig":   minisign.Sign(otherPriv, minisign.KeyID{8}, []byte(body), ""),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		sig    string
+		verify bool
+		want   string // error substring; "" for success
+	}{
+		{"valid", "/list.txt", "", true, ""},
+		{"valid gzip", "/list.txt.gz", "", true, ""},
+		{"explicit signature", "/forged.txt", ts.URL + "/list.txt.minisig", true, "does not match"},
+		{"modified list", "/forged.txt", "", true, "does not match"},
+		{"untrusted key", "/other.txt", "", true, "untrusted key"},
+		{"missing signature", "/list.txt", ts.URL + "/none.minisig", true, "404"},
+		{"verification skipped", "/other.txt", "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Source{Name: "feed", URL: ts.URL + tt.path, Format: FormatDomains, Signature: tt.sig, key: &key}
+			set := make(map[string]struct{})
+			n, _, err := fetchAndParse(context.Background(), ts.Client(), src, nil, tt.verify, set)
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("err = %v, want %q", err, tt.want)
+				}
+				if len(set) != 0 {
+					t.Errorf("unverified domains were added: %v", set)
+				}
+				return
+			}
+			if err != nil || n < 2 {
+				t.Fatalf("n = %d, err = %v", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Format は取得元の書式
+type Format string
+
+const (
+	FormatHosts   Format = "hosts"   // "0.0.0.0 bad.example.com"（StevenBlack/hosts など）
+	FormatDomains Format = "domains" // 1行 1ドメイン
+	FormatURLs    Format = "urls"    // 1行 1URL、ホスト部分を使う（URLhaus text、OpenPhish など）
+	FormatCSV     Format = "csv"     // CSV の 1列（URL またはドメイン。PhishTank、URLhaus csv など）
+	FormatAdblock Format = "adblock" // "||bad.example.com^" 形式のドメインルールのみ
+)
+
+// parseFunc は r を読み、見つけたドメイン候補を add に渡す。
+// 正規化と重複排除は add 側で行う。
+type parseFunc func(r io.Reader, src Source, add func(string)) error
+
+// parsers は書式ごとのパーサ
+var parsers = map[Format]parseFunc{
+	FormatHosts:   parseHosts,
+	FormatDomains: parseDomains,
+	FormatURLs:    parseURLs,
+	FormatCSV:     parseCSV,
+	FormatAdblock: parseAdblock,
+}
+
+// formatNames は対応書式の一覧（エラーメッセージ用）
+func formatNames() []string {
+	names := make([]string, 0, len(parsers))
+	for f := range parsers {
+		names = append(names, string(f))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hostsNames は hosts ファイルに定番で含まれる、ブロック対象ではない名前
+var hostsNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+}
+
+// scanLines はコメント（#）と空行を除いた行を fn に渡す
+func scanLines(r io.Reader, fn func(line string)) error {
+	sc := bufio.NewScanner(r)
+	// 1行が長い場合に備えてバッファ拡張
+	const maxLine = 1024 * 1024
+	buf := make([]byte, 0, 64*1024)
+	sc.Buffer(buf, maxLine)
+
+	for sc.Scan() {
+		line := sc.Text()
+		// 行末コメント（例: "0.0.0.0 bad.com # note"）も除去
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			fn(line)
+		}
+	}
+	return sc.Err()
+}
+
+// parseHosts は "IP ドメイン [ドメイン...]" 行からドメインを取り出す。
+// IP が無い行はドメインだけが書かれているものとみなす。
+func parseHosts(r io.Reader, _ Source, add func(string)) error {
+	return scanLines(r, func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, f := range fields {
+			if !hostsNames[strings.ToLower(f)] {
+				add(f)
+			}
+		}
+	})
+}
+
+// parseDomains は 1行 1ドメインのリストを読む
+func parseDomains(r io.Reader, _ Source, add func(string)) error {
+	return scanLines(r, func(line string) {
+		add(strings.Fields(line)[0])
+	})
+}
+
+// parseURLs は 1行 1URL のリストからホストを取り出す
+func parseURLs(r io.Reader, _ Source, add func(string)) error {
+	return scanLines(r, func(line string) {
+		add(hostOf(strings.Fields(line)[0]))
+	})
+}
+
+// parseCSV は src.Column の列を URL またはドメインとして読む。
+// 列名で指定した場合は最初の行をヘッダとみなす。"#" で始まる行はコメント。
+func parseCSV(r io.Reader, src Source, add func(string)) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	col := -1
+	if n, err := strconv.Atoi(src.Column); err == nil {
+		if n < 1 {
+			return fmt.Errorf("csv column %d: columns count from 1", n)
+		}
+		col = n - 1
+	}
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if col < 0 {
+			// ヘッダ行から列番号を決める
+			col = slices.IndexFunc(rec, func(h string) bool {
+				return strings.EqualFold(strings.TrimSpace(h), src.Column)
+			})
+			if col < 0 {
+				return fmt.Errorf("csv column %q not found in header", src.Column)
+			}
+			continue
+		}
+		if col < len(rec) {
+			add(hostOf(strings.TrimSpace(rec[col])))
+		}
+	}
+}
+
+// parseAdblock は "||domain^" 形式（オプション "$..." 付きも可）のルールだけを採用する。
+// 例外ルール（@@）、要素隠し（##）、パス付きのルールは対象外。
+func parseAdblock(r io.Reader, _ Source, add func(string)) error {
+	sc := bufio.NewScanner(r)
+	const maxLine = 1024 * 1024
+	sc.Buffer(make([]byte, 0, 64*1024), maxLine)
+
+	for sc.Scan() {
+		// コメント（!）、ヘッダ（[Adblock Plus 2.0]）、例外（@@）なども "||" で始まらない
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "||") {
+			continue
+		}
+		rule, _, _ := strings.Cut(line[2:], "$")
+		dom, ok := strings.CutSuffix(rule, "^")
+		if !ok || strings.ContainsAny(dom, "/*^|") {
+			continue
+		}
+		add(dom)
+	}
+	return sc.Err()
+}
+
+// hostOf は URL からホストを返す。スキームが無ければドメインとみなす。
+func hostOf(s string) string {
+	if s == "" {
+		return ""
+	}
+	if !strings.Contains(s, "://") {
+		// "bad.example.com/path" のようなスキーム無し URL
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
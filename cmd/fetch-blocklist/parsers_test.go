@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    Source
+		input  string
+		want   []string
+		errSub string
+	}{
+		{
+			name: "hosts",
+			src:  Source{Format: FormatHosts},
+			input: "# StevenBlack\n127.0.0.1 localhost\n0.0.0.0 0.0.0.0\n" +
+				"0.0.0.0 Ads.Example.com. # tracker\n0.0.0.0 a.test b.test\nplain.example.org\n",
+			want: []string{"a.test", "ads.example.com", "b.test", "plain.example.org"},
+		},
+		{
+			name:  "domains",
+			src:   Source{Format: FormatDomains},
+			input: "bad.example.com\n\n# comment\nEVIL.test  # note\n192.0.2.1\nnodot\n",
+			want:  []string{"bad.example.com", "evil.test"},
+		},
+		{
+			name: "urls",
+			src:  Source{Format: FormatURLs},
+			input: "https://login.bad.example/secure/#account\nhttp://192.0.2.7:8080/bin.sh\n" +
+				"hxxp://ignored\nphish.test/path?q=1\nHTTPS://Mixed.Example.NET\n",
+			want: []string{"login.bad.example", "mixed.example.net", "phish.test"},
+		},
+		{
+			name: "csv by header",
+			src:  Source{Format: FormatCSV, Column: "url"},
+			input: "phish_id,url,verified\n1,https://paypa1.example/login,yes\n" +
+				"2,\"http://bank.test/a,b\",yes\n3,,no\n",
+			want: []string{"bank.test", "paypa1.example"},
+		},
+		{
+			name: "csv by index",
+			src:  Source{Format: FormatCSV, Column: "3"},
+			input: "# id,dateadded,url,url_status\n" +
+				"\"1\",\"2024-01-01\",\"http://malware.test/x.exe\",\"online\"\n" +
+				"\"2\",\"2024-01-01\"\n",
+			want: []string{"malware.test"},
+		},
+		{
+			name:   "csv missing column",
+			src:    Source{Format: FormatCSV, Column: "domain"},
+			input:  "id,url\n1,https://a.test\n",
+			errSub: `column "domain" not found`,
+		},
+		{
+			name: "adblock",
+			src:  Source{Format: FormatAdblock},
+			input: "[Adblock Plus 2.0]\n! Title: ads\n||ads.example.com^\n||tracker.test^$third-party\n" +
+				"@@||allowed.test^\nexample.org##.banner\n||cdn.test/ads/*\n|https://x.test|\n",
+			want: []string{"ads.example.com", "tracker.test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := parsers[tt.src.Format](strings.NewReader(tt.input), tt.src, func(s string) {
+				if d := normalizeDomain(s); d != "" && !slices.Contains(got, d) {
+					got = append(got, d)
+				}
+			})
+			if tt.errSub != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errSub) {
+					t.Fatalf("error = %v, want %q", err, tt.errSub)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
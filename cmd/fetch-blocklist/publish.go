@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// リストの公開とバックアップ。
+//
+// 新しいリストは同じディレクトリの一時ファイルに書いて fsync してから rename で
+// 置き換えるので、途中でクラッシュしたりディスクが一杯になったりしても
+// data/<list>.txt が欠けたり途中で切れたりすることはない。
+// 置き換える前の世代は <list>.txt.backup-<UTC 時刻> として（.sha256 などの
+// 付随ファイルと一緒に）残し、新しいものから keep 個だけ保持する。
+
+// companions はリストと一緒に公開・退避・復元する付随ファイルの拡張子
+var companions = []string{".sha256", ".asc", ".minisig"}
+
+// backupTime はバックアップ名の時刻部分（辞書順 = 時刻順）
+const backupTime = "20060102T150405Z"
+
+// writeFileAtomic は path を data で置き換える（一時ファイル + fsync + rename）
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// 失敗時は一時ファイルを残さない（rename 後は存在しないので無害）
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir は rename をディスクに反映させる（対応しない OS では何もしない）
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// checksumLine は sha256sum -c 形式の <path>.sha256 の内容
+func checksumLine(path, sum string) string {
+	return sum + "  " + filepath.Base(path) + "\n"
+}
+
+// replacingPath は置き換え中の印のパス。publish と rollback は古い .sha256 を
+// 消す前にこの印へ新旧のチェックサム行を書き、新しい .sha256 を書いてから消す。
+func replacingPath(path string) string {
+	return path + ".replacing"
+}
+
+// beginReplace は path の新旧の .sha256 の内容（無ければ空）を印に残す
+func beginReplace(path string, checksums ...string) error {
+	var lines []string
+	for _, c := range checksums {
+		if c = strings.TrimSpace(c); c != "" {
+			lines = append(lines, c)
+		}
+	}
+	return writeFileAtomic(replacingPath(path), []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// endReplace は置き換えの印を消す
+func endReplace(path string) error {
+	if err := os.Remove(replacingPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readChecksum は path の .sha256 の内容を返す（無ければ空）
+func readChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path + ".sha256")
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// ensureChecksum は置き換えの途中で止まって .sha256 が無くなったリストの
+// チェックサムを戻す。リストが印に残した新旧どちらかのチェックサムと一致する
+// 場合だけ戻し、印が無い・一致しない場合は（手で編集された・改ざんされた
+// 可能性があるので）エラーにする。
+func ensureChecksum(path string) error {
+	if fileExists(path + ".sha256") {
+		// 置き換えは終わっている（印を消す前に止まった場合）
+		return endReplace(path)
+	}
+	if !fileExists(path) {
+		return endReplace(path)
+	}
+	marker, err := os.ReadFile(replacingPath(path))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s has no checksum and no interrupted update left it so; check the list and publish it again with --force", path)
+	}
+	if err != nil {
+		return err
+	}
+	sum, err := calculateFileChecksum(path)
+	if err != nil {
+		return err
+	}
+	for line := range strings.Lines(string(marker)) {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], sum) {
+			if err := writeFileAtomic(path+".sha256", []byte(strings.TrimSpace(line)+"\n"), 0o644); err != nil {
+				return err
+			}
+			fmt.Println("Restored checksum after an interrupted update:", path+".sha256")
+			return endReplace(path)
+		}
+	}
+	return fmt.Errorf("%s matches neither checksum recorded before the interrupted update; check the list and publish it again with --force", path)
+}
+
+// createBackup は現在のリストと付随ファイルを新しい世代として退避する。
+// 元のファイルはそのまま残す（置き換えは writeFileAtomic が行う）。
+func createBackup(path string, now time.Time) (string, error) {
+	backup := path + ".backup-" + now.UTC().Format(backupTime)
+	// 同じ秒に 2回公開した場合
+	for i := 2; fileExists(backup); i++ {
+		backup = fmt.Sprintf("%s.backup-%s-%d", path, now.UTC().Format(backupTime), i)
+	}
+	if err := copyFile(path, backup); err != nil {
+		return "", err
+	}
+	for _, ext := range companions {
+		if fileExists(path + ext) {
+			if err := copyFile(path+ext, backup+ext); err != nil {
+				return "", err
+			}
+		}
+	}
+	return backup, nil
+}
+
+// backups は path のバックアップを新しい順に返す（[0] が 1世代前）
+func backups(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := filepath.Base(path) + ".backup-"
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		// 取得元の名前が "backup-" で始まるとドメインのキャッシュも前方一致する
+		if !strings.HasPrefix(name, prefix) || e.IsDir() || strings.HasSuffix(name, ".cache") ||
+			slices.ContainsFunc(companions, func(ext string) bool { return strings.HasSuffix(name, ext) }) {
+			continue
+		}
+		out = append(out, filepath.Join(filepath.Dir(path), name))
+	}
+	slices.Sort(out)
+	slices.Reverse(out)
+	return out, nil
+}
+
+// pruneBackups は新しいものから keep 世代を残して古いバックアップを削除する
+func pruneBackups(path string, keep int) error {
+	list, err := backups(path)
+	if err != nil || len(list) <= keep {
+		return err
+	}
+	var errs []error
+	for _, b := range list[keep:] {
+		for _, ext := range append([]string{""}, companions...) {
+			if err := os.Remove(b + ext); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollback は n 世代前（1 = 直前）のバックアップをリストと付随ファイルに復元する。
+// 復元前の現在のリストも新しいバックアップとして残すので、rollback 自体も戻せる。
+func rollback(path string, n int) (string, error) {
+	list, err := backups(path)
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || n > len(list) {
+		return "", fmt.Errorf("no backup generation %d of %s (%d available)", n, path, len(list))
+	}
+	src := list[n-1]
+
+	if fileExists(path) {
+		if _, err := createBackup(path, time.Now()); err != nil {
+			return "", fmt.Errorf("back up current list: %w", err)
+		}
+	}
+
+	// 現在の付随ファイルを消し、リスト本体、古い世代の付随ファイルの順に置き換える。
+	// 途中で止まっても、新しいリストの隣に古いチェックサムや署名が残ることはない。
+	oldSum, err := readChecksum(path)
+	if err != nil {
+		return "", err
+	}
+	newSum, err := readChecksum(src)
+	if err != nil {
+		return "", err
+	}
+	if err := beginReplace(path, newSum, oldSum); err != nil {
+		return "", err
+	}
+	for _, ext := range companions {
+		if err := os.Remove(path + ext); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if err := restoreFile(src, path); err != nil {
+		return "", err
+	}
+	for _, ext := range companions {
+		if fileExists(src + ext) {
+			if err := restoreFile(src+ext, path+ext); err != nil {
+				return "", err
+			}
+		}
+	}
+	return src, endReplace(path)
+}
+
+// restoreFile は src の内容で dst をアトミックに置き換える
+func restoreFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data, 0o644)
+}
+
+// copyFile は src を dst にコピーして fsync する
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "list.txt")
+	for _, body := range []string{"old\n", "new\n"} {
+		if err := writeFileAtomic(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(p); string(data) != "new\n" {
+		t.Errorf("content = %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	// A failed write leaves the previous file intact
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "list.txt"), []byte("x"), 0o644); err == nil {
+		t.Errorf("expected error for missing directory")
+	}
+}
+
+// publishDomains publishes one generation of list.txt in dir
+func publishDomains(t *testing.T, path string, keep int, domains ...string) {
+	t.Helper()
+	set := make(map[string]struct{})
+	for _, d := range domains {
+		set[d] = struct{}{}
+	}
+	if err := publish(path, set, nil, options{skipVerify: true, backup: true, keep: keep}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+}
+
+func TestPublish_RotatesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	for _, d := range []string{"a.test", "b.test", "c.test", "d.test"} {
+		publishDomains(t, path, 2, d)
+	}
+
+	gens, err := backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gens) != 2 {
+		t.Fatalf("got %d backups, want 2: %v", len(gens), gens)
+	}
+	// Newest first: c.test, then b.test
+	for i, want := range []string{"c.test", "b.test"} {
+		got, _ := readDomains(gens[i])
+		if !slices.Equal(got, []string{want}) {
+			t.Errorf("generation %d = %v, want %s", i+1, got, want)
+		}
+		if _, err := os.Stat(gens[i] + ".sha256"); err != nil {
+			t.Errorf("generation %d has no checksum: %v", i+1, err)
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	if len(matches) != 6 { // list, checksum and two generations of both
+		t.Errorf("files = %v", matches)
+	}
+}
+
+func TestPublish_DropsOutdatedSignature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	publishDomains(t, path, 5, "a.test")
+	if err := os.WriteFile(path+".minisig", []byte("signature of a.test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	publishDomains(t, path, 5, "b.test")
+
+	if _, err := os.Stat(path + ".minisig"); !os.IsNotExist(err) {
+		t.Errorf("signature of the previous list left next to the new one: %v", err)
+	}
+	// The signature still belongs to the backed up generation
+	gens, _ := backups(path)
+	if data, err := os.ReadFile(gens[0] + ".minisig"); err != nil || string(data) != "signature of a.test\n" {
+		t.Errorf("backup signature = %q, %v", data, err)
+	}
+}
+
+func TestEnsureChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	publishDomains(t, path, 5, "a.test")
+	oldSum, _ := readChecksum(path)
+	newContent := []byte("b.test\n")
+	newSum := checksumLine(path, calculateChecksum(newContent))
+
+	// interrupt simulates a publish stopped after removing the old .sha256
+	interrupt := func(replaced bool) {
+		t.Helper()
+		if err := beginReplace(path, newSum, oldSum); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(path + ".sha256"); err != nil {
+			t.Fatal(err)
+		}
+		if replaced {
+			if err := writeFileAtomic(path, newContent, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	checksum := func() string {
+		data, _ := os.ReadFile(path + ".sha256")
+		return string(data)
+	}
+
+	// Stopped before the list was replaced: the old checksum comes back
+	interrupt(false)
+	if err := ensureChecksum(path); err != nil {
+		t.Fatal(err)
+	}
+	if checksum() != oldSum || fileExists(replacingPath(path)) {
+		t.Errorf("checksum = %q, marker left: %v", checksum(), fileExists(replacingPath(path)))
+	}
+
+	// Stopped after the list was replaced: the new checksum is written
+	interrupt(true)
+	if err := ensureChecksum(path); err != nil {
+		t.Fatal(err)
+	}
+	if checksum() != newSum || fileExists(replacingPath(path)) {
+		t.Errorf("checksum = %q, want %q", checksum(), newSum)
+	}
+
+	// A list edited after its checksum was deleted is not trusted again
+	if err := os.WriteFile(path, []byte("b.test\nedited.test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path + ".sha256"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureChecksum(path); err == nil || fileExists(path+".sha256") {
+		t.Errorf("ensureChecksum() = %v, restored a checksum for an edited list", err)
+	}
+
+	// Neither is one that does not match the interrupted update
+	if err := beginReplace(path, newSum, oldSum); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureChecksum(path); err == nil || fileExists(path+".sha256") {
+		t.Errorf("ensureChecksum() = %v, restored a checksum for a list matching neither side", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	publishDomains(t, path, 5, "a.test")
+	firstSum, _ := os.ReadFile(path + ".sha256")
+	publishDomains(t, path, 5, "b.test")
+	publishDomains(t, path, 5, "c.test")
+
+	restored, err := rollback(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readDomains(path); !slices.Equal(got, []string{"a.test"}) {
+		t.Errorf("after rollback --to 2: %v, want a.test", got)
+	}
+	if sum, _ := os.ReadFile(path + ".sha256"); string(sum) != string(firstSum) {
+		t.Errorf("checksum not restored: %q, want %q", sum, firstSum)
+	}
+	if err := verifyChecksum(path, strings.Fields(string(firstSum))[0]); err != nil {
+		t.Errorf("restored list does not match its checksum: %v", err)
+	}
+	if !strings.Contains(restored, ".backup-") {
+		t.Errorf("restored from %s", restored)
+	}
+
+	// The list replaced by the rollback is itself a generation now
+	if _, err := rollback(path, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readDomains(path); !slices.Equal(got, []string{"c.test"}) {
+		t.Errorf("after undoing the rollback: %v, want c.test", got)
+	}
+
+	if _, err := rollback(path, 99); err == nil || !strings.Contains(err.Error(), "no backup generation 99") {
+		t.Errorf("rollback --to 99: error = %v", err)
+	}
+}
+
+func TestCreateBackup_SameSecond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("a.test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first, err := createBackup(path, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := createBackup(path, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("backups collide: %s", first)
+	}
+	if gens, _ := backups(path); len(gens) != 2 || gens[0] != second {
+		t.Errorf("backups = %v, want %s first", gens, second)
+	}
+}
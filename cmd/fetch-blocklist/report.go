@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/psl"
+)
+
+// 前の世代との差分（--report）と大量削除の安全装置（--max-removed）。
+// 取得元の障害で空に近いリストが返ってきた場合などに、そのまま公開して
+// 大半のエントリを消してしまうのを防ぐ。
+
+// topDomainsLimit はレポートに載せる登録可能ドメインの数
+const topDomainsLimit = 10
+
+// domainChange は 1つの登録可能ドメイン（eTLD+1）配下の増減
+type domainChange struct {
+	Domain  string `json:"domain"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// listDiff はリストの前の世代と新しい内容の差分（JSON で <list>.txt.diff.json に保存）
+type listDiff struct {
+	List        string         `json:"list"`
+	Path        string         `json:"path"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Previous    int            `json:"previous"` // 前の世代のエントリ数
+	Current     int            `json:"current"`  // 新しいエントリ数
+	Added       []string       `json:"added"`
+	Removed     []string       `json:"removed"`
+	Top         []domainChange `json:"top_registrable_domains"` // 増減の多い順
+}
+
+// diffPath は差分ファイルのパス
+func diffPath(outputPath string) string {
+	return outputPath + ".diff.json"
+}
+
+// diffDomains は old と current（どちらもソート済み）を比べる
+func diffDomains(list, path string, old, current []string) listDiff {
+	d := listDiff{
+		List:        list,
+		Path:        path,
+		GeneratedAt: time.Now().UTC(),
+		Previous:    len(old),
+		Current:     len(current),
+		Added:       []string{},
+		Removed:     []string{},
+	}
+	i, j := 0, 0
+	for i < len(old) || j < len(current) {
+		switch {
+		case j == len(current) || (i < len(old) && old[i] < current[j]):
+			d.Removed = append(d.Removed, old[i])
+			i++
+		case i == len(old) || current[j] < old[i]:
+			d.Added = append(d.Added, current[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	d.Top = topDomains(d.Added, d.Removed, topDomainsLimit)
+	return d
+}
+
+// topDomains は増減を登録可能ドメインごとに集計し、多い順に n 件返す
+func topDomains(added, removed []string, n int) []domainChange {
+	byDomain := make(map[string]*domainChange)
+	count := func(domains []string, inc func(*domainChange)) {
+		for _, d := range domains {
+			reg := psl.Default().RegistrableDomain(d)
+			if reg == "" {
+				reg = d
+			}
+			c := byDomain[reg]
+			if c == nil {
+				c = &domainChange{Domain: reg}
+				byDomain[reg] = c
+			}
+			inc(c)
+		}
+	}
+	count(added, func(c *domainChange) { c.Added++ })
+	count(removed, func(c *domainChange) { c.Removed++ })
+
+	out := make([]domainChange, 0, len(byDomain))
+	for _, c := range byDomain {
+		out = append(out, *c)
+	}
+	slices.SortFunc(out, func(a, b domainChange) int {
+		if d := (b.Added + b.Removed) - (a.Added + a.Removed); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// removedPercent は前の世代のうち消えるエントリの割合（%）
+func (d listDiff) removedPercent() float64 {
+	if d.Previous == 0 {
+		return 0
+	}
+	return float64(len(d.Removed)) * 100 / float64(d.Previous)
+}
+
+// check は消えるエントリが maxRemoved % を超えていればエラーを返す（0 なら無効）
+func (d listDiff) check(maxRemoved float64) error {
+	if maxRemoved <= 0 || d.removedPercent() <= maxRemoved {
+		return nil
+	}
+	return fmt.Errorf("refusing to publish: %d of %d entries (%.1f%%) would be removed, more than --max-removed %g%%",
+		len(d.Removed), d.Previous, d.removedPercent(), maxRemoved)
+}
+
+// print は人が読む形式のレポートを書く
+func (d listDiff) print(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d -> %d domains (+%d added, -%d removed, %.1f%% of previous removed)\n",
+		d.List, d.Previous, d.Current, len(d.Added), len(d.Removed), d.removedPercent())
+	if len(d.Top) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  top registrable domains:")
+	width := 0
+	for _, c := range d.Top {
+		width = max(width, len(c.Domain))
+	}
+	for _, c := range d.Top {
+		fmt.Fprintf(w, "    %-*s  +%d -%d\n", width, c.Domain, c.Added, c.Removed)
+	}
+}
+
+// json は差分を JSON にする
+func (d listDiff) json() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
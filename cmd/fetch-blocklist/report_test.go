@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffDomains(t *testing.T) {
+	old := []string{"a.evil.test", "b.evil.test", "login.bank.co.uk", "x.example.com"}
+	current := []string{"a.evil.test", "c.evil.test", "d.evil.test", "pay.bank.co.uk", "x.example.com"}
+
+	d := diffDomains("phishing", "data/phishing.txt", old, current)
+	if !slices.Equal(d.Added, []string{"c.evil.test", "d.evil.test", "pay.bank.co.uk"}) {
+		t.Errorf("added = %v", d.Added)
+	}
+	if !slices.Equal(d.Removed, []string{"b.evil.test", "login.bank.co.uk"}) {
+		t.Errorf("removed = %v", d.Removed)
+	}
+	want := []domainChange{
+		{Domain: "evil.test", Added: 2, Removed: 1},
+		{Domain: "bank.co.uk", Added: 1, Removed: 1},
+	}
+	if !slices.Equal(d.Top, want) {
+		t.Errorf("top = %+v, want %+v", d.Top, want)
+	}
+	if p := d.removedPercent(); p != 50 {
+		t.Errorf("removedPercent = %v, want 50", p)
+	}
+
+	var out strings.Builder
+	d.print(&out)
+	for _, w := range []string{"phishing: 4 -> 5 domains (+3 added, -2 removed, 50.0% of previous removed)", "evil.test   +2 -1"} {
+		if !strings.Contains(out.String(), w) {
+			t.Errorf("report %q must contain %q", out.String(), w)
+		}
+	}
+
+	for _, tt := range []struct {
+		max  float64
+		fail bool
+	}{{0, false}, {50, false}, {49.9, true}} {
+		if err := d.check(tt.max); (err != nil) != tt.fail {
+			t.Errorf("check(%v) = %v", tt.max, err)
+		}
+	}
+}
+
+func TestPublish_MaxRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	publishDomains(t, path, 5, "a.test", "b.test", "c.test", "d.test")
+	before, _ := os.ReadFile(path)
+
+	// An upstream outage returning a single entry must not wipe the list
+	set := map[string]struct{}{"a.test": {}}
+	err := publish(path, set, nil, options{skipVerify: true, backup: true, keep: 5, maxRemoved: 50})
+	if err == nil || !strings.Contains(err.Error(), "3 of 4 entries (75.0%) would be removed") {
+		t.Fatalf("publish() = %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("list replaced despite the guard")
+	}
+	if gens, _ := backups(path); len(gens) != 0 {
+		t.Errorf("backups created for a refused update: %v", gens)
+	}
+
+	// Within the limit the update goes through, with a JSON diff
+	set["b.test"] = struct{}{}
+	if err := publish(path, set, nil, options{skipVerify: true, backup: true, keep: 5, maxRemoved: 50, report: true}); err != nil {
+		t.Fatalf("publish() = %v", err)
+	}
+	data, err := os.ReadFile(diffPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d listDiff
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.List != "list" || d.Previous != 4 || d.Current != 2 || len(d.Added) != 0 || !slices.Equal(d.Removed, []string{"c.test", "d.test"}) {
+		t.Errorf("diff = %+v", d)
+	}
+}
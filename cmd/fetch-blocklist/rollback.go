@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runRollback は `fetch-blocklist rollback` を実装し、終了コードを返す
+func runRollback(args []string) int {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	var (
+		to   = fs.Int("to", 1, "backup generation to restore (1 = the list before the last update)")
+		list = fs.String("list", defaultList, "list name, i.e. <dir>/<list>.txt")
+		dir  = fs.String("dir", defaultOutputDir, "directory holding the lists (output_dir of the sources file)")
+	)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  fetch-blocklist rollback [--to N] [--list blocklist] [--dir data]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	path := filepath.Join(*dir, *list+".txt")
+	restored, err := rollback(path, *to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fetch-blocklist rollback error:", err)
+		// 選べる世代を表示
+		if gens, _ := backups(path); len(gens) > 0 {
+			fmt.Fprintln(os.Stderr, "Available generations:")
+			for i, b := range gens {
+				fmt.Fprintf(os.Stderr, "  %d  %s\n", i+1, filepath.Base(b))
+			}
+		}
+		return 1
+	}
+	fmt.Printf("OK: restored %s from %s\n", path, filepath.Base(restored))
+	return 0
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
+	"gopkg.in/yaml.v3"
+)
+
+// 取得元の設定ファイル（--sources）。
+//
+//	output_dir: data
+//	sources:
+//	  - name: openphish
+//	    url: https://openphish.com/feed.txt
+//	    format: urls
+//	    list: phishing
+//	  - name: corp-intel
+//	    url: https://intel.example.com/domains.txt
+//	    format: domains
+//	    public_key: RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3
+//
+// 同じ list 名のソースは 1つのファイル <output_dir>/<list>.txt にまとめる。
+// public_key を設定したソースは minisign 署名（既定は <url>.minisig）を検証してから使う。
+
+// Source は 1つの取得元（URL またはローカルファイル）
+type Source struct {
+	Name   string `yaml:"name"`   // ログとヘッダ用。省略時は URL / パス
+	URL    string `yaml:"url"`    // http(s) の取得元
+	Path   string `yaml:"path"`   // ローカルファイル（URL の代わり）
+	Format Format `yaml:"format"` // hosts | domains | urls | csv | adblock
+	Column string `yaml:"column"` // csv のみ：列名（ヘッダ行あり）または 1 始まりの列番号
+	List   string `yaml:"list"`   // 出力先のリスト名（例: phishing → data/phishing.txt）
+
+	PublicKey string `yaml:"public_key"` // minisign 公開鍵、または .pub ファイルのパス
+	Signature string `yaml:"signature"`  // 署名の URL / パス。省略時は <url または path>.minisig
+
+	key *minisign.PublicKey // 検証済みの PublicKey（validate で設定）
+}
+
+// location は取得元の URL またはパス
+func (s Source) location() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return s.Path
+}
+
+// signature は署名ファイルの URL またはパス
+func (s Source) signature() string {
+	if s.Signature != "" {
+		return s.Signature
+	}
+	return s.location() + ".minisig"
+}
+
+// SourcesConfig は設定ファイル全体
+type SourcesConfig struct {
+	OutputDir string   `yaml:"output_dir"`
+	Sources   []Source `yaml:"sources"`
+}
+
+// デフォルトの出力先ディレクトリとリスト名
+const (
+	defaultOutputDir = "data"
+	defaultList      = "blocklist"
+)
+
+// defaultSources は --sources 未指定時の取得元（従来どおり data/blocklist.txt を生成）
+var defaultSources = []Source{{
+	Name:   "stevenblack",
+	URL:    "https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts",
+	Format: FormatHosts,
+	List:   defaultList,
+}}
+
+// listName はファイル名として安全なリスト名
+var listName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// loadSources は設定ファイルを読み込んで検証する。path が空ならデフォルト設定。
+func loadSources(path string) (*SourcesConfig, error) {
+	if path == "" {
+		return &SourcesConfig{OutputDir: defaultOutputDir, Sources: defaultSources}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg SourcesConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = defaultOutputDir
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate はソースごとの設定を検証し、省略値を補う
+func (c *SourcesConfig) validate() error {
+	if len(c.Sources) == 0 {
+		return errors.New("no sources configured")
+	}
+	var errs []error
+	names := make(map[string]bool, len(c.Sources))
+	for i := range c.Sources {
+		s := &c.Sources[i]
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("sources[%d]: %s", i, fmt.Sprintf(format, args...)))
+		}
+
+		switch {
+		case s.URL == "" && s.Path == "":
+			fail("url or path is required")
+		case s.URL != "" && s.Path != "":
+			fail("set either url or path, not both")
+		case s.URL != "" && !strings.HasPrefix(s.URL, "https://") && !strings.HasPrefix(s.URL, "http://"):
+			fail("url must be http or https, got %q", s.URL)
+		}
+		if s.Name == "" {
+			s.Name = s.location()
+		}
+		if names[s.Name] {
+			fail("name %q is used more than once", s.Name)
+		}
+		names[s.Name] = true
+
+		if _, ok := parsers[s.Format]; !ok {
+			fail("format must be one of %s, got %q", strings.Join(formatNames(), ", "), s.Format)
+		}
+		if s.Format == FormatCSV && s.Column == "" {
+			fail("column is required for csv")
+		}
+		if s.Format != FormatCSV && s.Column != "" {
+			fail("column is only supported for csv")
+		}
+
+		if s.PublicKey != "" {
+			keys, err := minisign.LoadPublicKeys([]string{s.PublicKey})
+			if err != nil {
+				fail("public_key: %v", err)
+			} else {
+				s.key = &keys[0]
+			}
+		}
+		if s.Signature != "" && s.PublicKey == "" {
+			fail("signature requires public_key")
+		}
+
+		if s.List == "" {
+			s.List = defaultList
+		}
+		if !listName.MatchString(s.List) {
+			fail("list %q must be lowercase letters, digits, '-' or '_'", s.List)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lists はリスト名を設定ファイルでの初出順に返す
+func (c *SourcesConfig) lists() []string {
+	var out []string
+	for _, s := range c.Sources {
+		if !slices.Contains(out, s.List) {
+			out = append(out, s.List)
+		}
+	}
+	return out
+}
+
+// sourcesOf は list に書き込むソースを返す
+func (c *SourcesConfig) sourcesOf(list string) []Source {
+	var out []Source
+	for _, s := range c.Sources {
+		if s.List == list {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadSources_Default(t *testing.T) {
+	cfg, err := loadSources("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputDir != "data" || !slices.Equal(cfg.lists(), []string{"blocklist"}) {
+		t.Errorf("default config = %+v", cfg)
+	}
+}
+
+func TestLoadSources_Errors(t *testing.T) {
+	dir := t.TempDir()
+	p := writeFile(t, dir, "sources.yaml", `
+sources:
+  - url: ftp://feeds.example/list.txt
+    format: hosts
+  - path: a.txt
+    format: xml
+    list: Phishing
+  - name: pt
+    path: pt.csv
+    format: csv
+  - name: pt
+    url: https://example.com/x
+    path: x.txt
+    format: urls
+    column: "2"
+  - path: b.txt
+    format: domains
+    public_key: RWQnot-a-key
+  - path: c.txt
+    format: domains
+    signature: c.txt.sig
+`)
+	_, err := loadSources(p)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{
+		`sources[0]: url must be http or https`,
+		`sources[1]: format must be one of adblock, csv, domains, hosts, urls, got "xml"`,
+		`sources[1]: list "Phishing"`,
+		`sources[2]: column is required for csv`,
+		`sources[3]: set either url or path`,
+		`sources[3]: name "pt" is used more than once`,
+		`sources[3]: column is only supported for csv`,
+		`sources[4]: public_key: "RWQnot-a-key" is neither a minisign public key nor a key file`,
+		`sources[5]: signature requires public_key`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q must contain %q", err, want)
+		}
+	}
+
+	unknown := writeFile(t, dir, "unknown.yaml", "sources:\n  - path: a.txt\n    format: hosts\n    weight: 10\n")
+	if _, err := loadSources(unknown); err == nil || !strings.Contains(err.Error(), "field weight not found") {
+		t.Errorf("unknown key: error = %v", err)
+	}
+}
+
+func TestRun_Lists(t *testing.T) {
+	dir := t.TempDir()
+	hosts := writeFile(t, dir, "hosts", "0.0.0.0 ads.example.com\n0.0.0.0 shared.test\n")
+	adblock := writeFile(t, dir, "ads.txt", "||tracker.test^\n||shared.test^\n")
+	phish := writeFile(t, dir, "phish.txt", "https://login.phish.test/a\n")
+	out := filepath.Join(dir, "out")
+	p := writeFile(t, dir, "sources.yaml", `
+output_dir: `+out+`
+sources:
+  - {name: hosts, path: `+hosts+`, format: hosts, list: ads}
+  - {name: phish, path: `+phish+`, format: urls, list: phishing}
+  - {name: adblock, path: `+adblock+`, format: adblock, list: ads}
+`)
+	cfg, err := loadSources(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), cfg, options{skipVerify: true, backup: true}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	for list, want := range map[string][]string{
+		"ads":      {"ads.example.com", "shared.test", "tracker.test"},
+		"phishing": {"login.phish.test"},
+	} {
+		data, err := os.ReadFile(filepath.Join(out, list+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" && !strings.HasPrefix(line, "#") {
+				got = append(got, line)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", list, got, want)
+		}
+		if _, err := os.Stat(filepath.Join(out, list+".txt.sha256")); err != nil {
+			t.Errorf("%s: checksum file missing: %v", list, err)
+		}
+	}
+}
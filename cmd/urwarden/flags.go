@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/logger"
+)
+
+// blocklistFlag collects repeated --blocklist values as named blocklist specs
+type blocklistFlag struct {
+	specs []config.BlocklistSpec
+}
+
+func (f *blocklistFlag) String() string {
+	paths := make([]string, 0, len(f.specs))
+	for _, s := range f.specs {
+		paths = append(paths, s.Path)
+	}
+	return strings.Join(paths, ";")
+}
+
+func (f *blocklistFlag) Set(val string) error {
+	spec, err := config.ParseBlocklistSpec(val)
+	if err != nil {
+		return err
+	}
+	f.specs = append(f.specs, spec)
+	return nil
+}
+
+// loadConfig builds the configuration with precedence
+// flags > environment > config file > defaults.
+// configPath falls back to URWARDEN_CONFIG; overrides maps flag names to
+// setters and is only consulted for flags given on the command line.
+func loadConfig(fs *flag.FlagSet, configPath string, overrides map[string]func(*config.Config)) (*config.Config, error) {
+	cfg := config.Default()
+
+	if configPath == "" {
+		configPath = os.Getenv("URWARDEN_CONFIG")
+	}
+	if configPath != "" {
+		if err := cfg.LoadFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.LoadFromEnv()
+
+	fs.Visit(func(f *flag.Flag) {
+		if set, ok := overrides[f.Name]; ok {
+			set(cfg)
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// setupLogging applies --log-format and --verbose to the global logger.
+// Without --verbose only records at quiet level and above are written.
+func setupLogging(cfg *config.Config, quiet logger.Level) {
+	if format, err := logger.ParseFormat(cfg.LogFormat); err == nil {
+		logger.Default.SetFormat(format)
+	}
+	if cfg.Verbose {
+		logger.Default.SetLevel(logger.LevelDebug)
+	} else {
+		logger.Default.SetLevel(quiet)
+	}
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/extract"
 	"github.com/samuraidays/urwarden/internal/input"
 	"github.com/samuraidays/urwarden/internal/logger"
 	"github.com/samuraidays/urwarden/internal/output"
-	"github.com/samuraidays/urwarden/internal/parse"
-	"github.com/samuraidays/urwarden/internal/rules"
+	"github.com/samuraidays/urwarden/internal/scan"
 	"github.com/samuraidays/urwarden/internal/score"
 	"github.com/samuraidays/urwarden/internal/version"
 )
@@ -19,32 +22,77 @@ const (
 	exitOK       = 0 // Success
 	exitInternal = 1 // Internal error (file I/O, unexpected exceptions)
 	exitInput    = 2 // Input error (invalid URLs, etc.)
+	exitVerdict  = 3 // A result met the --fail-on label
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	var (
 		showVersion bool
+		configPath  string
 		infile      string
 		verbose     bool
-		blocklist   string
+		blocklists  blocklistFlag
+		pslPath     string
+		allowlist   string
+		workers     int
+		ordered     bool
+		dedupe      bool
+		extractMode bool
+		extractFmt  string
+		defang      bool
+		outFormat   string
+		outPath     string
+		showSummary bool
+		failOn      string
+		onlyLabels  string
+		minScore    int
+		logFormat   string
 	)
 	flag.BoolVar(&showVersion, "version", false, "show version and exit")
+	flag.StringVar(&configPath, "config", "", "path to config file (.yaml, .yml, .toml or .json)")
 	flag.StringVar(&infile, "input", "", "path to file with URLs (one per line). Use '-' for stdin")
 	flag.BoolVar(&verbose, "verbose", false, "enable verbose logging")
-	flag.StringVar(&blocklist, "blocklist", "data/blocklist.txt", "path to blocklist file")
+	flag.StringVar(&logFormat, "log-format", "text", "log record format: text or json")
+	flag.Var(&blocklists, "blocklist", "blocklist `path` or name=..,path=..,category=..,weight=.. (repeatable; default data/blocklist.txt)")
+	flag.StringVar(&allowlist, "allowlist", "", "path to allowlist file (same format as the blocklist)")
+	flag.StringVar(&pslPath, "psl", "", "path to a public_suffix_list.dat overriding the embedded copy")
+	flag.IntVar(&workers, "workers", 1, "number of URLs scored concurrently")
+	flag.BoolVar(&ordered, "ordered", false, "keep input order in the output when --workers > 1")
+	flag.BoolVar(&extractMode, "extract", false, "find URLs anywhere in free text, .eml messages or HTML instead of reading one URL per line")
+	flag.StringVar(&extractFmt, "extract-format", "auto", "input format for --extract: auto, text, eml or html")
+	flag.StringVar(&outFormat, "format", "json", "output format: json, csv, tsv, sarif, stix or table")
+	flag.StringVar(&failOn, "fail-on", "", "exit with code 3 when any result is at least this label: suspicious or malicious")
+	flag.StringVar(&onlyLabels, "only-labels", "", "write only results with these comma-separated labels, e.g. malicious,suspicious")
+	flag.IntVar(&minScore, "min-score", 0, "write only results scoring at least `N`")
+	flag.BoolVar(&showSummary, "summary", false, "print counts per label and rule to stderr at the end (default for --format table)")
+	flag.StringVar(&outPath, "output", "", "write results to `file` instead of stdout")
+	flag.BoolVar(&defang, "defang", false, "defang URLs of suspicious and malicious results (hxxps://evil[.]com)")
+	flag.BoolVar(&dedupe, "dedupe", true, "skip URLs repeating one of the last 100000 distinct URLs")
 
 	// Custom usage message
 	flag.CommandLine.SetOutput(os.Stderr)
 	flag.Usage = func() {
 		fmt.Fprintln(os.Stderr, "Usage:")
-		fmt.Fprintln(os.Stderr, "  urwarden <URL> [<URL> ...] [--input file|-] [--version] [--verbose] [--blocklist path]")
+		fmt.Fprintln(os.Stderr, "  urwarden <URL> [<URL> ...] [--input file|-] [--config file] [--version] [--verbose] [--log-format text|json] [--blocklist path] [--allowlist path] [--extract [--extract-format auto|text|eml|html]] [--format json|csv|tsv|sarif|stix|table] [--only-labels labels] [--min-score N] [--summary] [--fail-on suspicious|malicious] [--output file] [--defang] [--workers N [--ordered]] [--dedupe=false]")
+		fmt.Fprintln(os.Stderr, "  urwarden serve [--config file] [--addr :8080] [--blocklist path]")
 		fmt.Fprintln(os.Stderr, "Examples:")
 		fmt.Fprintln(os.Stderr, "  urwarden 'https://bad.example.com/login'")
 		fmt.Fprintln(os.Stderr, "  urwarden --input urls.txt")
 		fmt.Fprintln(os.Stderr, "  cat urls.txt | urwarden --input -")
+		fmt.Fprintln(os.Stderr, "  urwarden --extract --input phishing.eml")
+		fmt.Fprintln(os.Stderr, "  urwarden --input urls.txt --format csv --output results.csv")
+		fmt.Fprintln(os.Stderr, "  urwarden --input urls.txt --only-labels malicious,suspicious --summary")
+		fmt.Fprintln(os.Stderr, "  urwarden --input huge.txt --workers 8 --ordered")
 		fmt.Fprintln(os.Stderr, "  urwarden --verbose --blocklist custom.txt example.com")
-		fmt.Fprintln(os.Stderr, "Exit codes: 0=ok, 1=internal error, 2=input error")
+		fmt.Fprintln(os.Stderr, "  urwarden --config urwarden.yaml --input urls.txt")
+		fmt.Fprintln(os.Stderr, "  urwarden --blocklist name=phishing,path=phishing.txt,category=phishing,weight=80 --blocklist name=ads,path=ads.txt,weight=10 example.com")
+		fmt.Fprintln(os.Stderr, "Exit codes: 0=ok, 1=internal error, 2=input error, 3=a result met --fail-on")
 	}
 
 	flag.Parse()
@@ -56,97 +104,183 @@ func main() {
 		os.Exit(exitOK)
 	}
 
-	// Initialize configuration
-	cfg := config.Default()
-	cfg.BlocklistPath = blocklist
-	cfg.Verbose = verbose
-	cfg.LoadFromEnv()
-
-	// Set up logging
-	if cfg.Verbose {
-		logger.Default.SetLevel(logger.LevelDebug)
-		logger.Info("starting urwarden v%s", version.Version)
-	} else {
-		// Disable all logging when not verbose
-		logger.Default.SetLevel(logger.LevelError + 1)
-	}
-
-	// Collect URLs from command line arguments or input file
-	urls, err := input.FromArgsOrInput(flag.Args(), infile, cfg)
+	// Initialize configuration (flags > env > file > defaults)
+	cfg, err := loadConfig(flag.CommandLine, configPath, map[string]func(*config.Config){
+		"blocklist":  func(c *config.Config) { c.Blocklists = blocklists.specs },
+		"allowlist":  func(c *config.Config) { c.AllowlistPath = allowlist },
+		"psl":        func(c *config.Config) { c.PublicSuffixListPath = pslPath },
+		"verbose":    func(c *config.Config) { c.Verbose = verbose },
+		"log-format": func(c *config.Config) { c.LogFormat = logFormat },
+		"workers":    func(c *config.Config) { c.Workers = workers },
+		"ordered":    func(c *config.Config) { c.PreserveOrder = ordered },
+		"dedupe":     func(c *config.Config) { c.Dedupe = dedupe },
+		"defang":     func(c *config.Config) { c.DefangOutput = defang },
+		"format":     func(c *config.Config) { c.OutputFormat = outFormat },
+		"fail-on":    func(c *config.Config) { c.FailOn = failOn },
+		"only-labels": func(c *config.Config) {
+			c.OnlyLabels = strings.FieldsFunc(onlyLabels, func(r rune) bool { return r == ',' || r == ' ' })
+		},
+		"min-score": func(c *config.Config) { c.MinScore = minScore },
+	})
 	if err != nil {
-		if cfg.Verbose {
-			logger.Error("failed to collect URLs: %v", err)
-		} else {
-			fmt.Fprintf(os.Stderr, "failed to collect URLs: %v\n", err)
-		}
-		os.Exit(exitInput)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInternal)
 	}
 
-	if len(urls) == 0 {
+	// Set up logging; everything is disabled when not verbose
+	setupLogging(cfg, logger.LevelOff)
+	logger.Info("starting urwarden", "version", version.Version)
+
+	if len(flag.Args()) == 0 && infile == "" {
 		flag.Usage()
 		os.Exit(exitInput)
 	}
-
-	if cfg.Verbose {
-		logger.Info("processing %d URLs", len(urls))
+	format, err := extract.ParseFormat(extractFmt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInput)
+	}
+	resultFormat, err := output.ParseFormat(cfg.OutputFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInput)
 	}
 
-	// Initialize rule evaluator
-	evaluator, err := rules.NewEvaluator(cfg.BlocklistPath, cfg)
+	// Initialize scanner (loads the blocklist once; shared by all workers)
+	scanner, err := scan.New(cfg)
 	if err != nil {
 		if cfg.Verbose {
-			logger.Error("failed to initialize rule evaluator: %v", err)
+			logger.Error("failed to initialize rule evaluator", "error", err)
 		} else {
 			fmt.Fprintf(os.Stderr, "failed to initialize rule evaluator: %v\n", err)
 		}
 		os.Exit(exitInternal)
 	}
 
-	// Process each URL
-	hadInputError := false
-	processedCount := 0
+	if cfg.Verbose {
+		logger.Info("processing URLs", "workers", cfg.Workers)
+	}
 
-	for _, inputURL := range urls {
-		if cfg.Verbose {
-			logger.Debug("processing URL: %s", inputURL)
+	// Stream URLs from command line arguments or input file through the worker pool
+	src := func(yield func(input.Item) error) error {
+		if extractMode {
+			return input.Extract(flag.Args(), infile, format, cfg, yield)
 		}
+		return input.Stream(flag.Args(), infile, cfg, yield)
+	}
+	opts := scan.StreamOptions{Workers: cfg.Workers, Ordered: cfg.PreserveOrder}
 
-		// Parse and normalize URL
-		norm, err := parse.NormalizeURL(inputURL)
+	// Results go to stdout or --output
+	dst := os.Stdout
+	if outPath != "" && outPath != "-" {
+		f, err := os.Create(outPath)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create output file: %v\n", err)
+			os.Exit(exitInternal)
+		}
+		dst = f
+	}
+	out := bufio.NewWriter(dst)
+	writer, err := output.New(resultFormat, out, output.Options{
+		Defang: cfg.DefangOutput,
+		Color:  useColor(dst),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInternal)
+	}
+	filter := scanner.Filter()
+	summary := output.NewSummary()
+	hadInputError := false
+	failed := 0 // results meeting --fail-on
+	writeFailed := false
+	itemCount := 0
+	processedCount := 0
+	filteredCount := 0
+
+	err = scanner.ScanStream(context.Background(), src, opts, func(o scan.Outcome) error {
+		itemCount++
+		if o.Err != nil {
 			if cfg.Verbose {
-				logger.Warn("failed to normalize URL %s: %v", inputURL, err)
+				logger.Warn("failed to normalize URL", "url", o.Item.URL, "source", o.Item.Source, "line", o.Item.Line, "error", o.Err)
 			} else {
-				fmt.Fprintf(os.Stderr, "failed to normalize URL %s: %v\n", inputURL, err)
+				fmt.Fprintf(os.Stderr, "failed to normalize URL %s: %v\n", o.Item.URL, o.Err)
 			}
 			hadInputError = true
-			continue
+			summary.AddError()
+			return nil
+		}
+		summary.Add(o.Result)
+		if cfg.FailOn != "" && score.AtLeast(o.Result.Label, cfg.FailOn) {
+			failed++
 		}
 
-		// Evaluate rules
-		reasons := evaluator.EvaluateAll(norm)
+		// Only actionable results are written; --fail-on and the summary see all
+		if !filter.Keep(o.Result) {
+			summary.AddFiltered()
+			filteredCount++
+			return nil
+		}
 
-		// Calculate score and label
-		total, label := score.Aggregate(reasons, cfg)
+		// Extracted URLs report where they were found; SARIF always needs locations
+		if extractMode || resultFormat == output.FormatSARIF {
+			o.Result.Location = o.Item.Location()
+		}
 
-		// Output result as JSON
-		if err := output.WriteResultJSON(inputURL, norm, total, label, reasons); err != nil {
-			if cfg.Verbose {
-				logger.Error("failed to write JSON output: %v", err)
-			} else {
-				fmt.Fprintf(os.Stderr, "failed to write JSON output: %v\n", err)
-			}
+		// Output result in the selected format
+		if err := writer.Write(o.Result); err != nil {
+			writeFailed = true
+			return fmt.Errorf("write output: %w", err)
+		}
+		processedCount++
+		return nil
+	})
+	closeErr := writer.Close()
+	if closeErr == nil {
+		closeErr = out.Flush()
+	}
+	if dst != os.Stdout {
+		if cerr := dst.Close(); closeErr == nil {
+			closeErr = cerr
+		}
+	}
+	if err == nil && closeErr != nil {
+		writeFailed = true
+		err = fmt.Errorf("write output: %w", closeErr)
+	}
+	if err != nil {
+		if cfg.Verbose {
+			logger.Error("failed to process URLs", "error", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "failed to process URLs: %v\n", err)
+		}
+		// Failing to write is internal; failing to read the input is an input error
+		if writeFailed {
 			os.Exit(exitInternal)
 		}
+		os.Exit(exitInput)
+	}
 
-		processedCount++
+	// Input without any URL is an error, unless URLs were searched for in free text
+	if itemCount == 0 && !extractMode {
+		flag.Usage()
+		os.Exit(exitInput)
 	}
 
 	if cfg.Verbose {
-		logger.Info("processed %d URLs successfully", processedCount)
+		logger.Info("processed URLs", "urls", processedCount+filteredCount, "filtered", filteredCount)
+	}
+	if showSummary || resultFormat == output.FormatTable {
+		_ = summary.Write(os.Stderr)
 	}
 
-	// Exit with appropriate code
+	// Exit with appropriate code: a verdict failure outranks input errors
+	if failed > 0 {
+		if cfg.Verbose {
+			logger.Warn("URLs met --fail-on", "urls", failed, "fail_on", cfg.FailOn)
+		}
+		os.Exit(exitVerdict)
+	}
 	if hadInputError {
 		if cfg.Verbose {
 			logger.Warn("some URLs could not be processed")
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/scan"
+	"github.com/samuraidays/urwarden/internal/server"
+	"github.com/samuraidays/urwarden/internal/version"
+)
+
+// runServe implements `urwarden serve` and returns the process exit code
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var (
+		configPath      string
+		addr            string
+		verbose         bool
+		blocklists      blocklistFlag
+		pslPath         string
+		allowlist       string
+		shutdownTimeout time.Duration
+		drainDelay      time.Duration
+		logFormat       string
+	)
+	fs.StringVar(&configPath, "config", "", "path to config file (.yaml, .yml, .toml or .json)")
+	fs.StringVar(&addr, "addr", "", "listen address (default :8080)")
+	fs.BoolVar(&verbose, "verbose", false, "enable verbose logging")
+	fs.StringVar(&logFormat, "log-format", "text", "log record format: text or json")
+	fs.Var(&blocklists, "blocklist", "blocklist `path` or name=..,path=..,category=..,weight=.. (repeatable; default data/blocklist.txt)")
+	fs.StringVar(&allowlist, "allowlist", "", "path to allowlist file (same format as the blocklist)")
+	fs.StringVar(&pslPath, "psl", "", "path to a public_suffix_list.dat overriding the embedded copy")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", 0, "time to wait for in-flight requests on shutdown (default 10s)")
+	fs.DurationVar(&drainDelay, "drain-delay", 0, "time /readyz reports 503 before shutdown starts, so load balancers stop routing here first")
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  urwarden serve [--config file] [--addr :8080] [--blocklist path] [--allowlist path] [--verbose] [--log-format text|json] [--shutdown-timeout 10s] [--drain-delay 5s]")
+		fmt.Fprintln(os.Stderr, "Endpoints:")
+		fmt.Fprintln(os.Stderr, `  POST /v1/score   {"url": "..."} or {"urls": ["...", ...]}`)
+		fmt.Fprintln(os.Stderr, "  GET  /healthz    liveness")
+		fmt.Fprintln(os.Stderr, "  GET  /readyz     readiness")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitInput
+	}
+
+	// Flags > env > file > defaults
+	cfg, err := loadConfig(fs, configPath, map[string]func(*config.Config){
+		"addr":             func(c *config.Config) { c.ListenAddr = addr },
+		"blocklist":        func(c *config.Config) { c.Blocklists = blocklists.specs },
+		"allowlist":        func(c *config.Config) { c.AllowlistPath = allowlist },
+		"psl":              func(c *config.Config) { c.PublicSuffixListPath = pslPath },
+		"shutdown-timeout": func(c *config.Config) { c.ShutdownTimeout = shutdownTimeout },
+		"drain-delay":      func(c *config.Config) { c.DrainDelay = drainDelay },
+		"verbose":          func(c *config.Config) { c.Verbose = verbose },
+		"log-format":       func(c *config.Config) { c.LogFormat = logFormat },
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitInternal
+	}
+
+	// The server always reports lifecycle events; --verbose adds debug output
+	setupLogging(cfg, logger.LevelInfo)
+
+	scanner, err := scan.New(cfg)
+	if err != nil {
+		logger.Error("failed to initialize rule evaluator", "error", err)
+		return exitInternal
+	}
+
+	srv := server.New(scanner, cfg)
+	httpServer := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.HTTPTimeout,
+		WriteTimeout:      cfg.HTTPTimeout,
+		IdleTimeout:       cfg.IdleConnTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "version", version.Version, "addr", cfg.ListenAddr)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		logger.Error("server failed", "error", err)
+		return exitInternal
+	case <-ctx.Done():
+	}
+
+	// Stop advertising readiness and give load balancers --drain-delay to
+	// notice before connections are closed; a second signal skips the wait
+	logger.Info("shutting down", "drain_delay", cfg.DrainDelay, "timeout", cfg.ShutdownTimeout)
+	srv.SetReady(false)
+	if cfg.DrainDelay > 0 {
+		again, cancelAgain := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-time.After(cfg.DrainDelay):
+		case <-again.Done():
+		}
+		cancelAgain()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed", "error", err)
+		return exitInternal
+	}
+	logger.Info("server stopped")
+	return exitOK
+}
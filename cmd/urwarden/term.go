@@ -0,0 +1,19 @@
+package main
+
+import "os"
+
+// useColor reports whether ANSI colours should be written to f: only for
+// terminals, and never when NO_COLOR is set (https://no-color.org)
+func useColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,139 @@
+package urwarden_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/samuraidays/urwarden"
+)
+
+func ExampleScanner_Scan() {
+	s, err := urwarden.New(urwarden.WithBlocklist("testdata/blocklist.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := s.Scan(context.Background(), "https://bad.example.com/login")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(res.Score, res.Label)
+	for _, r := range res.Reasons {
+		fmt.Println(r.Rule, r.Weight)
+	}
+	// Output:
+	// 80 malicious
+	// blocklist_hit 70
+	// path_has_login_like 10
+}
+
+func ExampleWithThresholds() {
+	s, err := urwarden.New(
+		urwarden.WithBlocklist("testdata/blocklist.txt"),
+		urwarden.WithThresholds(10, 50),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := s.Scan(context.Background(), "https://example.com/signin")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(res.Score, res.Label)
+	// Output: 10 suspicious
+}
+
+func ExampleScanner_Scan_invalidURL() {
+	s, err := urwarden.New(urwarden.WithBlocklist("testdata/blocklist.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = s.Scan(context.Background(), "ftp://example.com/")
+	fmt.Println(errors.Is(err, urwarden.ErrInvalidScheme))
+	// Output: true
+}
+
+// shortenerRule flags well-known URL shorteners, which hide the real destination
+type shortenerRule struct{}
+
+func (shortenerRule) Name() string       { return "url_shortener" }
+func (shortenerRule) DefaultWeight() int { return 15 }
+
+func (r shortenerRule) Evaluate(n urwarden.NormalizedURL) []urwarden.Reason {
+	switch n.Host {
+	case "bit.ly", "tinyurl.com":
+		return []urwarden.Reason{{Rule: r.Name(), Weight: r.DefaultWeight(), Detail: n.Host}}
+	}
+	return nil
+}
+
+func ExampleWithRule() {
+	s, err := urwarden.New(
+		urwarden.WithBlocklist("testdata/blocklist.txt"),
+		urwarden.WithRule(shortenerRule{}),
+		urwarden.WithDisabledRules(urwarden.RulePathHasLoginLike),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := s.Scan(context.Background(), "https://bit.ly/login")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, r := range res.Reasons {
+		fmt.Println(r.Rule, r.Weight, r.Detail)
+	}
+	// Output: url_shortener 15 bit.ly
+}
+
+func ExampleWithBlocklists() {
+	s, err := urwarden.New(urwarden.WithBlocklists(
+		urwarden.Blocklist{Name: "phishing", Path: "testdata/phishing.txt", Category: "phishing", Weight: 90},
+		urwarden.Blocklist{Name: "general", Path: "testdata/blocklist.txt"},
+	))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := s.Scan(context.Background(), "https://www.login-bad.example.net/")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, r := range res.Reasons {
+		fmt.Println(r.Rule, r.List, r.Category, r.Entry, r.Weight)
+	}
+	fmt.Println(res.Label)
+	// Output:
+	// blocklist_hit phishing phishing login-bad.example.net 90
+	// malicious
+}
+
+func ExampleScanner_Keep() {
+	s, err := urwarden.New(
+		urwarden.WithBlocklist("testdata/blocklist.txt"),
+		urwarden.WithOnlyLabels(urwarden.LabelMalicious, urwarden.LabelSuspicious),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, u := range []string{"https://bad.example.com/login", "https://example.com/"} {
+		res, err := s.Scan(context.Background(), u)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if s.Keep(res) {
+			fmt.Println(res.InputURL, res.Label)
+		}
+	}
+	// Output: https://bad.example.com/login malicious
+}
@@ -2,6 +2,7 @@ package blocklist
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,50 +16,71 @@ import (
 // Blocklist represents a cached blocklist with fast lookup capabilities
 type Blocklist struct {
 	domains map[string]struct{}
+	index   *node
 	mu      sync.RWMutex
 	path    string
+
+	integrity Integrity
+}
+
+// node is a single label in the reversed-label domain index.
+// "bad.example.com" is stored as root -> "com" -> "example" -> "bad".
+type node struct {
+	children map[string]*node
+	terminal bool // a blocklist entry ends at this label
 }
 
 // New creates a new blocklist instance
 func New(path string) *Blocklist {
 	return &Blocklist{
 		domains: make(map[string]struct{}),
+		index:   &node{},
 		path:    path,
 	}
 }
 
-// Load loads the blocklist from the specified file
-func (b *Blocklist) Load() error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Clear existing domains
-	b.domains = make(map[string]struct{})
+// NewVerified creates a blocklist whose Load refuses files failing check
+func NewVerified(path string, check Integrity) *Blocklist {
+	b := New(path)
+	b.integrity = check
+	return b
+}
 
+// Load loads the blocklist from the specified file. A missing file is an
+// empty list, unless Integrity checks are configured: then a missing or
+// unreadable file, like one failing the checks, is refused and the
+// previously loaded domains are kept.
+func (b *Blocklist) Load() error {
 	path := b.path
 	if path == "" {
 		path = "data/blocklist.txt"
 	}
 
-	logger.Debug("loading blocklist from: %s", path)
+	logger.Debug("loading blocklist", "path", path)
 
-	// Open the file
-	f, err := os.Open(filepath.Clean(path))
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil && b.integrity.enabled() {
+		// Deleting the list must not be a way around its checks
+		return fmt.Errorf("%s: %w", path, err)
+	}
 	if err != nil {
 		// File doesn't exist - this is not an error, just an empty blocklist
-		logger.Debug("blocklist file not found: %s", path)
+		logger.Debug("blocklist file not found", "path", path)
+		b.mu.Lock()
+		b.domains = make(map[string]struct{})
+		b.index = &node{}
+		b.mu.Unlock()
 		return nil
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			logger.Debug("failed to close blocklist file: %v", err)
-		}
-	}()
+	if err := b.integrity.check(path, data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
 
 	// Read and parse the file
-	scanner := bufio.NewScanner(f)
+	domains := make(map[string]struct{})
+	index := &node{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	lineCount := 0
-	domainCount := 0
 
 	for scanner.Scan() {
 		lineCount++
@@ -85,39 +107,106 @@ func (b *Blocklist) Load() error {
 			continue
 		}
 
-		b.domains[normalized] = struct{}{}
-		domainCount++
+		if _, dup := domains[normalized]; dup {
+			continue
+		}
+		domains[normalized] = struct{}{}
+		index.insert(normalized)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("error reading blocklist: %w", err)
 	}
 
-	logger.Debug("loaded %d domains from blocklist (%d lines processed)", domainCount, lineCount)
+	b.mu.Lock()
+	b.domains = domains
+	b.index = index
+	b.mu.Unlock()
+
+	logger.Debug("loaded blocklist", "path", path, "domains", len(domains), "lines", lineCount)
 	return nil
 }
 
-// Contains checks if a domain is in the blocklist
+// Contains checks if a domain is in the blocklist.
+// An exact entry wins; otherwise the closest listed parent domain is returned.
+// Lookups walk the label index, so the cost is O(labels in host).
 func (b *Blocklist) Contains(host string) (bool, string) {
+	return b.ContainsWithin(host, "")
+}
+
+// ContainsWithin is like Contains, but parent entries only match when they
+// are at or below apex (normally the registrable domain). With apex
+// "foo.github.io", an entry for "github.io" does not match
+// "x.foo.github.io", since everything under a public suffix is registered
+// independently. An empty apex allows any parent.
+func (b *Blocklist) ContainsWithin(host, apex string) (bool, string) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
 	// Normalize the host
 	host = strings.ToLower(strings.Trim(host, "."))
+	apex = strings.ToLower(strings.Trim(apex, "."))
+	if host == "" {
+		return false, ""
+	}
 
 	// Check for exact match
 	if _, exists := b.domains[host]; exists {
 		return true, host
 	}
 
-	// Check for subdomain matches
-	for domain := range b.domains {
-		if strings.HasSuffix(host, "."+domain) {
-			return true, domain
+	// Parent matches must start at or before this offset
+	limit := len(host)
+	switch {
+	case apex == "":
+	case host == apex:
+		limit = 0
+	case strings.HasSuffix(host, "."+apex):
+		limit = len(host) - len(apex)
+	}
+
+	// Walk from the TLD towards the leftmost label, remembering the
+	// deepest listed parent seen on the way
+	match := -1
+	n := b.index
+	end := len(host)
+	for end > 0 {
+		start := strings.LastIndexByte(host[:end], '.') + 1
+		n = n.children[host[start:end]]
+		if n == nil {
+			break
 		}
+		if n.terminal && start > 0 && start <= limit {
+			match = start
+		}
+		end = start - 1
+	}
+
+	if match < 0 {
+		return false, ""
 	}
+	return true, host[match:]
+}
 
-	return false, ""
+// insert adds a normalized domain to the index, one label at a time
+// starting from the rightmost one.
+func (n *node) insert(domain string) {
+	end := len(domain)
+	for end > 0 {
+		start := strings.LastIndexByte(domain[:end], '.') + 1
+		label := domain[start:end]
+		child := n.children[label]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[string]*node)
+			}
+			child = &node{}
+			n.children[label] = child
+		}
+		n = child
+		end = start - 1
+	}
+	n.terminal = true
 }
 
 // Size returns the number of domains in the blocklist
@@ -1,9 +1,16 @@
 package blocklist
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
 )
 
 func TestBlocklist(t *testing.T) {
@@ -117,3 +124,224 @@ func TestBlocklistNonexistentFile(t *testing.T) {
 		t.Errorf("Size() = %d, want 0", size)
 	}
 }
+
+func TestBlocklistNestedEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	blocklistFile := filepath.Join(tmpDir, "nested_blocklist.txt")
+
+	testData := `example.com
+bad.example.com
+`
+	if err := os.WriteFile(blocklistFile, []byte(testData), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	bl := New(blocklistFile)
+	if err := bl.Load(); err != nil {
+		t.Fatalf("failed to load blocklist: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		expected bool
+		domain   string
+	}{
+		{"example.com", true, "example.com"},
+		{"www.example.com", true, "example.com"},
+		{"x.bad.example.com", true, "bad.example.com"},
+		{"Sub.Bad.Example.Com.", true, "bad.example.com"},
+		{"com", false, ""},
+		{"notexample.com", false, ""},
+		{"", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			contains, domain := bl.Contains(tt.host)
+			if contains != tt.expected {
+				t.Errorf("Contains(%q) = %v, want %v", tt.host, contains, tt.expected)
+			}
+			if domain != tt.domain {
+				t.Errorf("Contains(%q) domain = %q, want %q", tt.host, domain, tt.domain)
+			}
+		})
+	}
+}
+
+// shippedBlocklist loads data/blocklist.txt for benchmarks, skipping when it is absent.
+func shippedBlocklist(b *testing.B) *Blocklist {
+	b.Helper()
+	path := filepath.Join("..", "..", "data", "blocklist.txt")
+	if _, err := os.Stat(path); err != nil {
+		b.Skipf("shipped blocklist not available: %v", err)
+	}
+	bl := New(path)
+	if err := bl.Load(); err != nil {
+		b.Fatalf("failed to load blocklist: %v", err)
+	}
+	return bl
+}
+
+// benchHosts mixes exact hits, subdomain hits and misses.
+var benchHosts = []string{
+	"www.example.com",
+	"cdn.static.assets.example.org",
+	"login.microsoftonline.com",
+	"a.b.c.d.unknown-host.net",
+}
+
+func BenchmarkContains(b *testing.B) {
+	bl := shippedBlocklist(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bl.Contains(benchHosts[i%len(benchHosts)])
+	}
+}
+
+// BenchmarkContainsLinearScan measures the previous map-scan lookup as a baseline.
+func BenchmarkContainsLinearScan(b *testing.B) {
+	bl := shippedBlocklist(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		host := benchHosts[i%len(benchHosts)]
+		if _, ok := bl.domains[host]; ok {
+			continue
+		}
+		for domain := range bl.domains {
+			if strings.HasSuffix(host, "."+domain) {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	path := filepath.Join("..", "..", "data", "blocklist.txt")
+	if _, err := os.Stat(path); err != nil {
+		b.Skipf("shipped blocklist not available: %v", err)
+	}
+	for i := 0; i < b.N; i++ {
+		if err := New(path).Load(); err != nil {
+			b.Fatalf("failed to load blocklist: %v", err)
+		}
+	}
+}
+
+func TestBlocklistContainsWithin(t *testing.T) {
+	tmpDir := t.TempDir()
+	blocklistFile := filepath.Join(tmpDir, "psl_blocklist.txt")
+
+	testData := `github.io
+bad.github.io
+evil.co.uk
+`
+	if err := os.WriteFile(blocklistFile, []byte(testData), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	bl := New(blocklistFile)
+	if err := bl.Load(); err != nil {
+		t.Fatalf("failed to load blocklist: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		apex     string
+		expected bool
+		domain   string
+	}{
+		{"github.io", "", true, "github.io"},
+		{"foo.github.io", "foo.github.io", false, ""},
+		{"x.foo.github.io", "foo.github.io", false, ""},
+		{"x.bad.github.io", "bad.github.io", true, "bad.github.io"},
+		{"bad.github.io", "bad.github.io", true, "bad.github.io"},
+		{"login.evil.co.uk", "evil.co.uk", true, "evil.co.uk"},
+		{"foo.github.io", "", true, "github.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+"@"+tt.apex, func(t *testing.T) {
+			contains, domain := bl.ContainsWithin(tt.host, tt.apex)
+			if contains != tt.expected {
+				t.Errorf("ContainsWithin(%q, %q) = %v, want %v", tt.host, tt.apex, contains, tt.expected)
+			}
+			if domain != tt.domain {
+				t.Errorf("ContainsWithin(%q, %q) domain = %q, want %q", tt.host, tt.apex, domain, tt.domain)
+			}
+		})
+	}
+}
+
+func TestBlocklistIntegrity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	good := []byte("bad.example.com\n")
+	sum := sha256.Sum256(good)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := minisign.PublicKey{ID: minisign.KeyID{1}, Key: pub}
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	write := func(name string, data []byte) {
+		t.Helper()
+		if err := os.WriteFile(path+name, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("", good)
+	write(".sha256", []byte(hex.EncodeToString(sum[:])+"  blocklist.txt\n"))
+	write(".minisig", minisign.Sign(priv, key.ID, good, "file:blocklist.txt"))
+
+	check := Integrity{Checksum: true, PublicKeys: []minisign.PublicKey{key}}
+	bl := NewVerified(path, check)
+	if err := bl.Load(); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if bl.Size() != 1 {
+		t.Fatalf("Size() = %d, want 1", bl.Size())
+	}
+
+	tests := []struct {
+		name  string
+		setup func()
+		check Integrity
+		want  string
+	}{
+		{"list changed", func() { write("", []byte("bad.example.com\nevil.test\n")) }, Integrity{Checksum: true}, "checksum mismatch"},
+		{"checksum missing", func() { _ = os.Remove(path + ".sha256") }, Integrity{Checksum: true}, "checksum"},
+		{"signature over other data", func() { write("", []byte("evil.test\n")) }, Integrity{PublicKeys: check.PublicKeys}, "does not match"},
+		{"untrusted key", func() { write(".minisig", minisign.Sign(otherPriv, minisign.KeyID{2}, good, "")) }, Integrity{PublicKeys: check.PublicKeys}, "untrusted key"},
+		{"signature missing", func() { _ = os.Remove(path + ".minisig") }, Integrity{PublicKeys: check.PublicKeys}, "signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			bl.integrity = tt.check
+			err := bl.Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() = %v, want error containing %q", err, tt.want)
+			}
+			// The previously loaded list stays in use
+			if ok, _ := bl.Contains("bad.example.com"); !ok || bl.Size() != 1 {
+				t.Errorf("refused load replaced the list (size %d)", bl.Size())
+			}
+		})
+	}
+
+	// Without checks the same files load
+	if err := New(path).Load(); err != nil {
+		t.Errorf("Load() without checks = %v", err)
+	}
+
+	// A missing list is an empty list only when nothing is checked
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	for _, check := range []Integrity{{Checksum: true}, {PublicKeys: check.PublicKeys}} {
+		if err := NewVerified(missing, check).Load(); err == nil || !os.IsNotExist(errors.Unwrap(err)) {
+			t.Errorf("Load() of a missing list with %+v = %v, want a not-exist error", check, err)
+		}
+	}
+	if err := NewVerified(missing, Integrity{}).Load(); err != nil {
+		t.Errorf("Load() of a missing list without checks = %v", err)
+	}
+}
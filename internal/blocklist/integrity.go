@@ -0,0 +1,61 @@
+package blocklist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
+)
+
+// Integrity lists the checks Load runs on a list file before using it. The
+// companion files are the ones cmd/fetch-blocklist writes (.sha256) and the
+// ones `minisign -Sm <list>` writes (.minisig). The zero value checks nothing.
+type Integrity struct {
+	Checksum   bool                 // require <list>.sha256 to match the file
+	PublicKeys []minisign.PublicKey // when set, require <list>.minisig signed by one of these
+}
+
+// enabled reports whether any check is configured
+func (i Integrity) enabled() bool {
+	return i.Checksum || len(i.PublicKeys) > 0
+}
+
+// check verifies data, the contents of the list at path
+func (i Integrity) check(path string, data []byte) error {
+	if i.Checksum {
+		if err := checkSum(path, data); err != nil {
+			return err
+		}
+	}
+	if len(i.PublicKeys) > 0 {
+		sig, err := os.ReadFile(path + ".minisig")
+		if err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+		if err := minisign.Verify(i.PublicKeys, data, sig); err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkSum compares data with the "<sha256 hex>  <name>" line in path.sha256
+func checkSum(path string, data []byte) error {
+	line, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return errors.New("checksum: empty .sha256 file")
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(fields[0], got) {
+		return fmt.Errorf("checksum mismatch: .sha256 has %s, file is %s", fields[0], got)
+	}
+	return nil
+}
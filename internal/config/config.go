@@ -1,31 +1,166 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
 )
 
 // Config holds all configuration for urwarden
 type Config struct {
 	// File paths
-	BlocklistPath string
+	BlocklistPath        string          // single blocklist, used when Blocklists is empty
+	Blocklists           []BlocklistSpec // named blocklists with their own weights
+	PublicSuffixListPath string          // overrides the embedded Public Suffix List when set
+	AllowlistPath        string          // never flag these domains (blocklist format); empty disables
+
+	// Blocklist integrity: lists failing a check are refused instead of used
+	VerifyChecksums bool     // require a matching <list>.sha256 next to each blocklist
+	ListPublicKeys  []string // minisign public keys or .pub files; require a <list>.minisig by one of them
+
+	// Allowlist scoring policy
+	AllowlistPolicy   string // AllowlistForceBenign | AllowlistCapScore
+	AllowlistScoreCap int    // score ceiling for AllowlistCapScore; 0 means just below SuspiciousThreshold
 
 	// Scoring thresholds
 	MaliciousThreshold  int
 	SuspiciousThreshold int
 
+	// Rules skipped by the evaluator (by rule name)
+	DisabledRules []string
+
+	// Per-rule weight overrides (by rule name); unset rules keep their default
+	RuleWeights map[string]int
+
+	// Rule inputs
+	SuspiciousTLDs   []string // public suffixes flagged by suspicious_tld
+	LoginKeywords    []string // path/query keywords flagged by path_has_login_like
+	ProtectedDomains []string // brand domains protected by the idn_homograph rule
+
 	// Performance settings
 	MaxLineLength int
 	BufferSize    int
 
+	// Batch processing (CLI)
+	Workers       int  // URLs scored concurrently
+	PreserveOrder bool // emit results in input order when Workers > 1
+	Dedupe        bool // skip repeated input URLs
+	DedupeWindow  int  // distinct URLs Dedupe remembers; older ones may be scored again
+
+	// Result filters applied after scoring (CLI, server and library)
+	OnlyLabels []string // keep only these labels; empty keeps all
+	MinScore   int      // keep only results scoring at least this
+
+	// CI gating: exit with a distinct code when a result is at least this label
+	FailOn string // "" (disabled) | suspicious | malicious
+
+	// Output
+	OutputFormat string // json | csv | tsv | sarif | stix | table
+	DefangOutput bool   // defang URLs of suspicious and malicious results
+
 	// HTTP client settings
 	HTTPTimeout     time.Duration
 	MaxIdleConns    int
 	IdleConnTimeout time.Duration
 
+	// Server settings (urwarden serve)
+	ListenAddr      string
+	MaxBatchSize    int
+	MaxRequestBytes int64
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration // time /readyz reports 503 before connections are closed
+
 	// Logging
-	Verbose bool
+	Verbose   bool
+	LogFormat string // text | json
+
+	// Malformed URWARDEN_* values, reported by Validate
+	envErrors []error
+}
+
+// BlocklistSpec describes one named blocklist
+type BlocklistSpec struct {
+	Name     string // reported in reasons; defaults to the file name without extension
+	Path     string
+	Category string // free-form, e.g. phishing | malware | ads
+	Weight   int    // score points for a hit; 0 uses the blocklist_hit default
+}
+
+// ParseBlocklistSpec parses a blocklist given on the command line or in
+// URWARDEN_BLOCKLISTS. Either a bare path, or comma-separated key=value
+// pairs with keys name, path, category and weight:
+//
+//	data/phishing.txt
+//	name=phishing,path=data/phishing.txt,category=phishing,weight=80
+func ParseBlocklistSpec(s string) (BlocklistSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return BlocklistSpec{}, fmt.Errorf("empty blocklist spec")
+	}
+
+	var spec BlocklistSpec
+	if !strings.Contains(s, "=") {
+		spec.Path = s
+	} else {
+		for _, pair := range strings.Split(s, ",") {
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return BlocklistSpec{}, fmt.Errorf("blocklist spec %q: expected key=value, got %q", s, pair)
+			}
+			key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+			switch key {
+			case "name":
+				spec.Name = val
+			case "path":
+				spec.Path = val
+			case "category":
+				spec.Category = val
+			case "weight":
+				w, err := strconv.Atoi(val)
+				if err != nil || w < 0 {
+					return BlocklistSpec{}, fmt.Errorf("blocklist spec %q: invalid weight %q", s, val)
+				}
+				spec.Weight = w
+			default:
+				return BlocklistSpec{}, fmt.Errorf("blocklist spec %q: unknown key %q", s, key)
+			}
+		}
+	}
+
+	if spec.Path == "" {
+		return BlocklistSpec{}, fmt.Errorf("blocklist spec %q: path is required", s)
+	}
+	if spec.Name == "" {
+		base := filepath.Base(spec.Path)
+		spec.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return spec, nil
+}
+
+// Allowlist policies applied by score.Aggregate when an allowlist entry matches
+const (
+	AllowlistForceBenign = "benign" // label benign, score kept for reference
+	AllowlistCapScore    = "cap"    // score capped at AllowlistScoreCap, label derived from it
+)
+
+// Labels lists the labels assigned by score.Aggregate, least severe first
+var Labels = []string{"benign", "suspicious", "malicious"}
+
+// BuiltinRules lists the names of the rules registered by rules.NewEvaluator.
+// Configuration files may only refer to these names.
+var BuiltinRules = []string{
+	"blocklist_hit",
+	"suspicious_tld",
+	"path_has_login_like",
+	"idn_homograph",
+	"allowlisted",
 }
 
 // Default returns a default configuration
@@ -34,33 +169,193 @@ func Default() *Config {
 		BlocklistPath:       "data/blocklist.txt",
 		MaliciousThreshold:  70,
 		SuspiciousThreshold: 30,
-		MaxLineLength:       1024 * 1024,
-		BufferSize:          64 * 1024,
-		HTTPTimeout:         30 * time.Second,
-		MaxIdleConns:        100,
-		IdleConnTimeout:     30 * time.Second,
-		Verbose:             false,
+		AllowlistPolicy:     AllowlistForceBenign,
+		SuspiciousTLDs: []string{
+			"xyz", "top", "click", "help", "shop",
+			"live", "cam", "kim", "fit", "country",
+		},
+		LoginKeywords: []string{
+			"login", "signin", "verify", "update", "password", "passcode",
+			"secure", "confirm", "invoice", "billing",
+		},
+		ProtectedDomains: []string{
+			"google.com", "apple.com", "microsoft.com", "amazon.com", "paypal.com",
+			"facebook.com", "instagram.com", "netflix.com", "github.com", "linkedin.com",
+		},
+		MaxLineLength:   1024 * 1024,
+		BufferSize:      64 * 1024,
+		Workers:         1,
+		Dedupe:          true,
+		DedupeWindow:    100_000,
+		OutputFormat:    "json",
+		HTTPTimeout:     30 * time.Second,
+		MaxIdleConns:    100,
+		IdleConnTimeout: 30 * time.Second,
+		ListenAddr:      ":8080",
+		MaxBatchSize:    1000,
+		MaxRequestBytes: 1 << 20,
+		ShutdownTimeout: 10 * time.Second,
+		Verbose:         false,
+		LogFormat:       "text",
 	}
 }
 
-// LoadFromEnv loads configuration from environment variables
+// LoadFromEnv loads configuration from environment variables. Values that
+// cannot be parsed are left out and reported by Validate.
 func (c *Config) LoadFromEnv() {
 	if val := os.Getenv("URWARDEN_BLOCKLIST_PATH"); val != "" {
+		// The single path replaces named blocklists from a config file,
+		// which would otherwise take priority
 		c.BlocklistPath = val
+		c.Blocklists = nil
+	}
+	if val := os.Getenv("URWARDEN_PSL_PATH"); val != "" {
+		c.PublicSuffixListPath = val
 	}
-	if val := os.Getenv("URWARDEN_MALICIOUS_THRESHOLD"); val != "" {
-		if threshold, err := strconv.Atoi(val); err == nil {
-			c.MaliciousThreshold = threshold
+	if val := os.Getenv("URWARDEN_BLOCKLISTS"); val != "" {
+		// Specs contain commas themselves, so lists are separated by ";"
+		var specs []BlocklistSpec
+		for _, item := range strings.Split(val, ";") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			spec, err := ParseBlocklistSpec(item)
+			if err != nil {
+				c.envErrors = append(c.envErrors, fmt.Errorf("URWARDEN_BLOCKLISTS: %w", err))
+				continue
+			}
+			specs = append(specs, spec)
 		}
+		c.Blocklists = specs
+	}
+	c.envBool("URWARDEN_VERIFY_CHECKSUMS", &c.VerifyChecksums)
+	if val := os.Getenv("URWARDEN_LIST_PUBLIC_KEYS"); val != "" {
+		c.ListPublicKeys = splitList(val)
+	}
+	if val := os.Getenv("URWARDEN_ALLOWLIST_PATH"); val != "" {
+		c.AllowlistPath = val
+	}
+	if val := os.Getenv("URWARDEN_ALLOWLIST_POLICY"); val != "" {
+		c.AllowlistPolicy = val
+	}
+	c.envInt("URWARDEN_MALICIOUS_THRESHOLD", &c.MaliciousThreshold)
+	c.envInt("URWARDEN_SUSPICIOUS_THRESHOLD", &c.SuspiciousThreshold)
+	if val := os.Getenv("URWARDEN_DISABLED_RULES"); val != "" {
+		c.DisabledRules = splitList(val)
 	}
-	if val := os.Getenv("URWARDEN_SUSPICIOUS_THRESHOLD"); val != "" {
-		if threshold, err := strconv.Atoi(val); err == nil {
-			c.SuspiciousThreshold = threshold
+	if val := os.Getenv("URWARDEN_PROTECTED_DOMAINS"); val != "" {
+		c.ProtectedDomains = splitList(val)
+	}
+	c.envInt("URWARDEN_WORKERS", &c.Workers)
+	if val := os.Getenv("URWARDEN_LISTEN_ADDR"); val != "" {
+		c.ListenAddr = val
+	}
+	if val := os.Getenv("URWARDEN_ONLY_LABELS"); val != "" {
+		c.OnlyLabels = splitList(val)
+	}
+	c.envInt("URWARDEN_MIN_SCORE", &c.MinScore)
+	if val := os.Getenv("URWARDEN_FAIL_ON"); val != "" {
+		c.FailOn = val
+	}
+	if val := os.Getenv("URWARDEN_FORMAT"); val != "" {
+		c.OutputFormat = val
+	}
+	c.envBool("URWARDEN_DEFANG", &c.DefangOutput)
+	c.envBool("URWARDEN_VERBOSE", &c.Verbose)
+	if val := os.Getenv("URWARDEN_LOG_FORMAT"); val != "" {
+		c.LogFormat = val
+	}
+}
+
+// envInt sets *dst from the integer in the environment variable name
+func (c *Config) envInt(name string, dst *int) {
+	if val := os.Getenv(name); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			c.envErrors = append(c.envErrors, fmt.Errorf("%s: %q is not an integer", name, val))
+			return
+		}
+		*dst = n
+	}
+}
+
+// envBool sets *dst from the boolean in the environment variable name
+func (c *Config) envBool(name string, dst *bool) {
+	if val := os.Getenv(name); val != "" {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			c.envErrors = append(c.envErrors, fmt.Errorf("%s: %q is not true or false", name, val))
+			return
+		}
+		*dst = b
+	}
+}
+
+// Validate checks the merged configuration (defaults, file, env and flags)
+// for values that cannot work together
+func (c *Config) Validate() error {
+	errs := slices.Clone(c.envErrors)
+	if c.SuspiciousThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("suspicious threshold (%d) must be positive", c.SuspiciousThreshold))
+	}
+	if c.SuspiciousThreshold >= c.MaliciousThreshold {
+		errs = append(errs, fmt.Errorf("suspicious threshold (%d) must be below malicious threshold (%d)", c.SuspiciousThreshold, c.MaliciousThreshold))
+	}
+	switch c.AllowlistPolicy {
+	case AllowlistForceBenign, AllowlistCapScore:
+	default:
+		errs = append(errs, fmt.Errorf("allowlist policy %q must be %q or %q", c.AllowlistPolicy, AllowlistForceBenign, AllowlistCapScore))
+	}
+	if c.AllowlistScoreCap < 0 {
+		errs = append(errs, fmt.Errorf("allowlist score cap (%d) must not be negative", c.AllowlistScoreCap))
+	}
+	for _, l := range c.OnlyLabels {
+		if !slices.Contains(Labels, l) {
+			errs = append(errs, fmt.Errorf("label %q must be one of %s", l, strings.Join(Labels, ", ")))
+		}
+	}
+	if c.MinScore < 0 {
+		errs = append(errs, fmt.Errorf("minimum score (%d) must not be negative", c.MinScore))
+	}
+	switch c.FailOn {
+	case "", "suspicious", "malicious":
+	default:
+		errs = append(errs, fmt.Errorf("fail-on label %q must be suspicious or malicious", c.FailOn))
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log format %q must be text or json", c.LogFormat))
+	}
+	if c.Workers < 1 {
+		errs = append(errs, fmt.Errorf("workers (%d) must be at least 1", c.Workers))
+	}
+	for name, w := range c.RuleWeights {
+		if w < 0 {
+			errs = append(errs, fmt.Errorf("weight of rule %s (%d) must not be negative", name, w))
+		}
+	}
+	if _, err := minisign.LoadPublicKeys(c.ListPublicKeys); err != nil {
+		errs = append(errs, fmt.Errorf("list public keys: %w", err))
+	}
+	seen := make(map[string]struct{}, len(c.Blocklists))
+	for _, b := range c.Blocklists {
+		if b.Path == "" {
+			errs = append(errs, fmt.Errorf("blocklist %q: path is required", b.Name))
+		}
+		if _, dup := seen[b.Name]; dup {
+			errs = append(errs, fmt.Errorf("blocklist name %q is used more than once", b.Name))
 		}
+		seen[b.Name] = struct{}{}
 	}
-	if val := os.Getenv("URWARDEN_VERBOSE"); val != "" {
-		if verbose, err := strconv.ParseBool(val); err == nil {
-			c.Verbose = verbose
+	return errors.Join(errs...)
+}
+
+// splitList splits a comma-separated value, dropping empty items
+func splitList(val string) []string {
+	var out []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
 		}
 	}
+	return out
 }
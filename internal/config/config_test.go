@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/config"
+)
+
+func TestParseBlocklistSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    config.BlocklistSpec
+		wantErr bool
+	}{
+		{in: "data/blocklist.txt", want: config.BlocklistSpec{Name: "blocklist", Path: "data/blocklist.txt"}},
+		{
+			in:   "name=phishing,path=lists/p.txt,category=phishing,weight=80",
+			want: config.BlocklistSpec{Name: "phishing", Path: "lists/p.txt", Category: "phishing", Weight: 80},
+		},
+		{in: "path=lists/ads.txt, weight=5", want: config.BlocklistSpec{Name: "ads", Path: "lists/ads.txt", Weight: 5}},
+		{in: "", wantErr: true},
+		{in: "name=x", wantErr: true},
+		{in: "path=x.txt,weight=high", wantErr: true},
+		{in: "path=x.txt,weight=-1", wantErr: true},
+		{in: "path=x.txt,colour=red", wantErr: true},
+		{in: "path=x.txt,oops", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := config.ParseBlocklistSpec(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBlocklistSpec(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseBlocklistSpec(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv_Blocklists(t *testing.T) {
+	t.Setenv("URWARDEN_BLOCKLISTS", "name=phishing,path=p.txt,weight=90;a.txt")
+	cfg := config.Default()
+	cfg.LoadFromEnv()
+	if len(cfg.Blocklists) != 2 || cfg.Blocklists[0].Weight != 90 || cfg.Blocklists[1].Name != "a" {
+		t.Fatalf("unexpected blocklists: %+v", cfg.Blocklists)
+	}
+}
+
+func TestLoadFromEnv_InvalidBlocklists(t *testing.T) {
+	t.Setenv("URWARDEN_BLOCKLISTS", "name=phishing,path=p.txt,weight=heavy;a.txt;")
+	cfg := config.Default()
+	cfg.LoadFromEnv()
+	if len(cfg.Blocklists) != 1 || cfg.Blocklists[0].Path != "a.txt" {
+		t.Errorf("blocklists = %+v", cfg.Blocklists)
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `URWARDEN_BLOCKLISTS: blocklist spec "name=phishing,path=p.txt,weight=heavy": invalid weight "heavy"`) {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestLoadFromEnv_BlocklistPathOverridesFile(t *testing.T) {
+	p := writeConfig(t, "urwarden.yaml", "blocklists:\n  - name: phishing\n    path: p.txt\n")
+	t.Setenv("URWARDEN_BLOCKLIST_PATH", "env.txt")
+	cfg := config.Default()
+	if err := cfg.LoadFile(p); err != nil {
+		t.Fatal(err)
+	}
+	cfg.LoadFromEnv()
+	if cfg.BlocklistPath != "env.txt" || len(cfg.Blocklists) != 0 {
+		t.Errorf("blocklist path = %q, blocklists = %+v; the environment must win", cfg.BlocklistPath, cfg.Blocklists)
+	}
+}
+
+func TestLoadFromEnv_Malformed(t *testing.T) {
+	t.Setenv("URWARDEN_WORKERS", "eight")
+	t.Setenv("URWARDEN_VERBOSE", "yes please")
+	cfg := config.Default()
+	cfg.LoadFromEnv()
+	if cfg.Workers != 1 || cfg.Verbose {
+		t.Errorf("malformed values must not be applied: workers=%d verbose=%v", cfg.Workers, cfg.Verbose)
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, w := range []string{`URWARDEN_WORKERS: "eight" is not an integer`, `URWARDEN_VERBOSE: "yes please" is not true or false`} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q must contain %q", err, w)
+		}
+	}
+}
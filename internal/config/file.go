@@ -0,0 +1,389 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig mirrors the configuration file layout. Pointer fields
+// distinguish "not set" from zero values so that only keys present in the
+// file override the defaults.
+type fileConfig struct {
+	Blocklist         *string             `yaml:"blocklist" toml:"blocklist"`
+	Blocklists        []fileBlocklist     `yaml:"blocklists" toml:"blocklists"`
+	Allowlist         *string             `yaml:"allowlist" toml:"allowlist"`
+	VerifyChecksums   *bool               `yaml:"verify_checksums" toml:"verify_checksums"`
+	ListPublicKeys    *[]string           `yaml:"list_public_keys" toml:"list_public_keys"`
+	AllowlistPolicy   *string             `yaml:"allowlist_policy" toml:"allowlist_policy"`
+	AllowlistScoreCap *int                `yaml:"allowlist_score_cap" toml:"allowlist_score_cap"`
+	PublicSuffixList  *string             `yaml:"public_suffix_list" toml:"public_suffix_list"`
+	Thresholds        fileThresholds      `yaml:"thresholds" toml:"thresholds"`
+	Rules             map[string]fileRule `yaml:"rules" toml:"rules"`
+	SuspiciousTLDs    *[]string           `yaml:"suspicious_tlds" toml:"suspicious_tlds"`
+	LoginKeywords     *[]string           `yaml:"login_keywords" toml:"login_keywords"`
+	ProtectedDomains  *[]string           `yaml:"protected_domains" toml:"protected_domains"`
+	Workers           *int                `yaml:"workers" toml:"workers"`
+	Ordered           *bool               `yaml:"ordered" toml:"ordered"`
+	Dedupe            *bool               `yaml:"dedupe" toml:"dedupe"`
+	OnlyLabels        *[]string           `yaml:"only_labels" toml:"only_labels"`
+	MinScore          *int                `yaml:"min_score" toml:"min_score"`
+	FailOn            *string             `yaml:"fail_on" toml:"fail_on"`
+	Format            *string             `yaml:"format" toml:"format"`
+	Defang            *bool               `yaml:"defang" toml:"defang"`
+	Server            fileServer          `yaml:"server" toml:"server"`
+	Verbose           *bool               `yaml:"verbose" toml:"verbose"`
+	LogFormat         *string             `yaml:"log_format" toml:"log_format"`
+}
+
+type fileBlocklist struct {
+	Name     string `yaml:"name" toml:"name"`
+	Path     string `yaml:"path" toml:"path"`
+	Category string `yaml:"category" toml:"category"`
+	Weight   int    `yaml:"weight" toml:"weight"`
+}
+
+type fileThresholds struct {
+	Suspicious *int `yaml:"suspicious" toml:"suspicious"`
+	Malicious  *int `yaml:"malicious" toml:"malicious"`
+}
+
+type fileRule struct {
+	Enabled *bool `yaml:"enabled" toml:"enabled"`
+	Weight  *int  `yaml:"weight" toml:"weight"`
+}
+
+type fileServer struct {
+	ListenAddr      *string        `yaml:"listen_addr" toml:"listen_addr"`
+	MaxBatchSize    *int           `yaml:"max_batch_size" toml:"max_batch_size"`
+	MaxRequestBytes *int64         `yaml:"max_request_bytes" toml:"max_request_bytes"`
+	ShutdownTimeout *time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	DrainDelay      *time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// LoadFile applies a YAML (.yaml, .yml), TOML (.toml) or JSON (.json)
+// configuration file on top of c. Keys absent from the file leave c
+// unchanged. Unknown keys, type mismatches and invalid values are rejected
+// with "file:line: message" errors; all problems found are reported together.
+func (c *Config) LoadFile(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml", ".json", ".toml":
+		// JSON is parsed by the YAML decoder, which accepts it as a subset
+	default:
+		return fmt.Errorf("config %s: unsupported format %q (use .yaml, .yml, .toml or .json)", path, ext)
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	var (
+		fc   fileConfig
+		line lineFunc
+	)
+	if ext == ".toml" {
+		line, err = decodeTOML(path, data, &fc)
+	} else {
+		line, err = decodeYAML(path, data, &fc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fc.validate(path, line); err != nil {
+		return err
+	}
+	fc.apply(c)
+	return nil
+}
+
+// lineFunc returns the line of the value at keys (mapping keys or sequence
+// indexes) in a configuration file
+type lineFunc func(keys ...string) int
+
+// decodeYAML strictly decodes a YAML or JSON file into fc
+func decodeYAML(path string, data []byte, fc *fileConfig) (lineFunc, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fileError(path, err)
+	}
+	if len(root.Content) == 0 {
+		// Empty file: nothing to apply
+		return func(...string) int { return 1 }, nil
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(fc); err != nil {
+		return nil, fileError(path, err)
+	}
+	doc := root.Content[0]
+	return func(keys ...string) int { return lineOf(doc, keys...) }, nil
+}
+
+// fileError rewrites yaml errors ("yaml: line 3: ...") as "path:3: ..."
+func fileError(path string, err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make([]error, 0, len(typeErr.Errors))
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, fmt.Errorf("%s:%s", path, strings.TrimPrefix(msg, "line ")))
+		}
+		return errors.Join(errs...)
+	}
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	if rest, ok := strings.CutPrefix(msg, "line "); ok {
+		return fmt.Errorf("%s:%s", path, rest)
+	}
+	return fmt.Errorf("%s: %s", path, msg)
+}
+
+// validate checks values that decode fine but make no sense, reporting the
+// line of the offending key
+func (fc *fileConfig) validate(path string, lineOf lineFunc) error {
+	type lineError struct {
+		line int
+		msg  string
+	}
+	var found []lineError
+	fail := func(keys []string, format string, args ...any) {
+		found = append(found, lineError{
+			line: lineOf(keys...),
+			msg:  strings.Join(keys, ".") + ": " + fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Thresholds, including the file's values against each other
+	if t := fc.Thresholds.Suspicious; t != nil && *t <= 0 {
+		fail([]string{"thresholds", "suspicious"}, "must be positive, got %d", *t)
+	}
+	if t := fc.Thresholds.Malicious; t != nil && *t <= 0 {
+		fail([]string{"thresholds", "malicious"}, "must be positive, got %d", *t)
+	}
+	if s, m := fc.Thresholds.Suspicious, fc.Thresholds.Malicious; s != nil && m != nil && *s >= *m {
+		fail([]string{"thresholds", "suspicious"}, "%d must be below thresholds.malicious (%d)", *s, *m)
+	}
+
+	if p := fc.AllowlistPolicy; p != nil && *p != AllowlistForceBenign && *p != AllowlistCapScore {
+		fail([]string{"allowlist_policy"}, "must be %q or %q, got %q", AllowlistForceBenign, AllowlistCapScore, *p)
+	}
+	if v := fc.AllowlistScoreCap; v != nil && *v < 0 {
+		fail([]string{"allowlist_score_cap"}, "must not be negative, got %d", *v)
+	}
+
+	// Blocklists
+	names := make(map[string]struct{}, len(fc.Blocklists))
+	for i, b := range fc.Blocklists {
+		item := []string{"blocklists", fmt.Sprint(i)}
+		if b.Path == "" {
+			fail(item, "path is required")
+		}
+		if b.Weight < 0 {
+			fail(append(item, "weight"), "must not be negative, got %d", b.Weight)
+		}
+		name := blocklistName(b)
+		if _, dup := names[name]; dup {
+			fail(item, "name %q is used more than once", name)
+		}
+		names[name] = struct{}{}
+	}
+
+	if v := fc.ListPublicKeys; v != nil {
+		for i, k := range *v {
+			if _, err := minisign.LoadPublicKeys([]string{k}); err != nil {
+				fail([]string{"list_public_keys", fmt.Sprint(i)}, "%v", err)
+			}
+		}
+	}
+
+	// Rules
+	for _, name := range slices.Sorted(maps.Keys(fc.Rules)) {
+		r := fc.Rules[name]
+		if !slices.Contains(BuiltinRules, name) {
+			fail([]string{"rules", name}, "unknown rule (known: %s)", strings.Join(BuiltinRules, ", "))
+			continue
+		}
+		if r.Weight != nil && name == "allowlisted" {
+			fail([]string{"rules", name, "weight"}, "not supported; use allowlist_policy")
+		}
+		if r.Weight != nil && *r.Weight < 0 {
+			fail([]string{"rules", name, "weight"}, "must not be negative, got %d", *r.Weight)
+		}
+	}
+
+	// Lists
+	for _, l := range []struct {
+		key  string
+		list *[]string
+	}{
+		{"suspicious_tlds", fc.SuspiciousTLDs},
+		{"login_keywords", fc.LoginKeywords},
+		{"protected_domains", fc.ProtectedDomains},
+	} {
+		if l.list == nil {
+			continue
+		}
+		for i, item := range *l.list {
+			if strings.TrimSpace(item) == "" {
+				fail([]string{l.key, fmt.Sprint(i)}, "must not be empty")
+			}
+		}
+	}
+
+	if v := fc.OnlyLabels; v != nil {
+		for i, l := range *v {
+			if !slices.Contains(Labels, l) {
+				fail([]string{"only_labels", fmt.Sprint(i)}, "must be one of %s, got %q", strings.Join(Labels, ", "), l)
+			}
+		}
+	}
+	if v := fc.MinScore; v != nil && *v < 0 {
+		fail([]string{"min_score"}, "must not be negative, got %d", *v)
+	}
+	if v := fc.LogFormat; v != nil && *v != "text" && *v != "json" {
+		fail([]string{"log_format"}, "must be text or json, got %q", *v)
+	}
+	if v := fc.FailOn; v != nil && *v != "suspicious" && *v != "malicious" {
+		fail([]string{"fail_on"}, "must be suspicious or malicious, got %q", *v)
+	}
+
+	// Batch processing
+	if v := fc.Workers; v != nil && *v < 1 {
+		fail([]string{"workers"}, "must be at least 1, got %d", *v)
+	}
+
+	// Server
+	if v := fc.Server.MaxBatchSize; v != nil && *v <= 0 {
+		fail([]string{"server", "max_batch_size"}, "must be positive, got %d", *v)
+	}
+	if v := fc.Server.MaxRequestBytes; v != nil && *v <= 0 {
+		fail([]string{"server", "max_request_bytes"}, "must be positive, got %d", *v)
+	}
+	if v := fc.Server.ShutdownTimeout; v != nil && *v < 0 {
+		fail([]string{"server", "shutdown_timeout"}, "must not be negative, got %s", *v)
+	}
+	if v := fc.Server.DrainDelay; v != nil && *v < 0 {
+		fail([]string{"server", "drain_delay"}, "must not be negative, got %s", *v)
+	}
+
+	// Report in file order
+	slices.SortStableFunc(found, func(a, b lineError) int { return a.line - b.line })
+	errs := make([]error, 0, len(found))
+	for _, e := range found {
+		errs = append(errs, fmt.Errorf("%s:%d: %s", path, e.line, e.msg))
+	}
+	return errors.Join(errs...)
+}
+
+// apply copies the values present in the file into c
+func (fc *fileConfig) apply(c *Config) {
+	setIf(&c.BlocklistPath, fc.Blocklist)
+	setIf(&c.AllowlistPath, fc.Allowlist)
+	setIf(&c.VerifyChecksums, fc.VerifyChecksums)
+	setIf(&c.ListPublicKeys, fc.ListPublicKeys)
+	setIf(&c.AllowlistPolicy, fc.AllowlistPolicy)
+	setIf(&c.AllowlistScoreCap, fc.AllowlistScoreCap)
+	setIf(&c.PublicSuffixListPath, fc.PublicSuffixList)
+	setIf(&c.SuspiciousThreshold, fc.Thresholds.Suspicious)
+	setIf(&c.MaliciousThreshold, fc.Thresholds.Malicious)
+	setIf(&c.SuspiciousTLDs, fc.SuspiciousTLDs)
+	setIf(&c.LoginKeywords, fc.LoginKeywords)
+	setIf(&c.ProtectedDomains, fc.ProtectedDomains)
+	setIf(&c.Workers, fc.Workers)
+	setIf(&c.PreserveOrder, fc.Ordered)
+	setIf(&c.Dedupe, fc.Dedupe)
+	setIf(&c.OnlyLabels, fc.OnlyLabels)
+	setIf(&c.MinScore, fc.MinScore)
+	setIf(&c.FailOn, fc.FailOn)
+	setIf(&c.OutputFormat, fc.Format)
+	setIf(&c.DefangOutput, fc.Defang)
+	setIf(&c.ListenAddr, fc.Server.ListenAddr)
+	setIf(&c.MaxBatchSize, fc.Server.MaxBatchSize)
+	setIf(&c.MaxRequestBytes, fc.Server.MaxRequestBytes)
+	setIf(&c.ShutdownTimeout, fc.Server.ShutdownTimeout)
+	setIf(&c.DrainDelay, fc.Server.DrainDelay)
+	setIf(&c.Verbose, fc.Verbose)
+	setIf(&c.LogFormat, fc.LogFormat)
+
+	if len(fc.Blocklists) > 0 {
+		c.Blocklists = make([]BlocklistSpec, 0, len(fc.Blocklists))
+		for _, b := range fc.Blocklists {
+			c.Blocklists = append(c.Blocklists, BlocklistSpec{
+				Name:     blocklistName(b),
+				Path:     b.Path,
+				Category: b.Category,
+				Weight:   b.Weight,
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(fc.Rules)) {
+		r := fc.Rules[name]
+		if r.Weight != nil {
+			if c.RuleWeights == nil {
+				c.RuleWeights = make(map[string]int)
+			}
+			c.RuleWeights[name] = *r.Weight
+		}
+		if r.Enabled != nil {
+			c.DisabledRules = slices.DeleteFunc(c.DisabledRules, func(n string) bool { return n == name })
+			if !*r.Enabled {
+				c.DisabledRules = append(c.DisabledRules, name)
+			}
+		}
+	}
+}
+
+func setIf[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func blocklistName(b fileBlocklist) string {
+	if b.Name != "" {
+		return b.Name
+	}
+	base := filepath.Base(b.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// lineOf returns the line of the node at keys (mapping keys or sequence
+// indexes), falling back to the deepest node found
+func lineOf(n *yaml.Node, keys ...string) int {
+	line := n.Line
+	for _, key := range keys {
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					line = n.Content[i].Line
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			var idx int
+			if _, err := fmt.Sscanf(key, "%d", &idx); err == nil && idx < len(n.Content) {
+				next = n.Content[idx]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return line
+}
@@ -0,0 +1,240 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/config"
+)
+
+func writeConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return p
+}
+
+func TestLoadFile_YAML(t *testing.T) {
+	p := writeConfig(t, "urwarden.yaml", `
+blocklists:
+  - name: phishing
+    path: p.txt
+    category: phishing
+    weight: 80
+  - path: lists/ads.txt
+thresholds:
+  suspicious: 40
+rules:
+  suspicious_tld:
+    enabled: false
+  path_has_login_like:
+    weight: 15
+suspicious_tlds: [zip, mov]
+server:
+  shutdown_timeout: 3s
+  drain_delay: 5s
+`)
+	cfg := config.Default()
+	if err := cfg.LoadFile(p); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if len(cfg.Blocklists) != 2 || cfg.Blocklists[0].Weight != 80 || cfg.Blocklists[1].Name != "ads" {
+		t.Errorf("blocklists = %+v", cfg.Blocklists)
+	}
+	if cfg.SuspiciousThreshold != 40 || cfg.MaliciousThreshold != 70 {
+		t.Errorf("thresholds = %d/%d, want 40/70", cfg.SuspiciousThreshold, cfg.MaliciousThreshold)
+	}
+	if len(cfg.DisabledRules) != 1 || cfg.DisabledRules[0] != "suspicious_tld" {
+		t.Errorf("disabled rules = %v", cfg.DisabledRules)
+	}
+	if cfg.RuleWeights["path_has_login_like"] != 15 {
+		t.Errorf("rule weights = %v", cfg.RuleWeights)
+	}
+	if strings.Join(cfg.SuspiciousTLDs, ",") != "zip,mov" {
+		t.Errorf("suspicious tlds = %v", cfg.SuspiciousTLDs)
+	}
+	if len(cfg.LoginKeywords) == 0 {
+		t.Errorf("login keywords must keep their defaults")
+	}
+	if cfg.ShutdownTimeout != 3*time.Second || cfg.DrainDelay != 5*time.Second {
+		t.Errorf("shutdown timeout = %s, drain delay = %s", cfg.ShutdownTimeout, cfg.DrainDelay)
+	}
+}
+
+func TestLoadFile_JSON(t *testing.T) {
+	p := writeConfig(t, "urwarden.json", `{
+  "allowlist": "allow.txt",
+  "allowlist_policy": "cap",
+  "thresholds": {"suspicious": 20, "malicious": 60}
+}`)
+	cfg := config.Default()
+	if err := cfg.LoadFile(p); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if cfg.AllowlistPath != "allow.txt" || cfg.AllowlistPolicy != "cap" || cfg.MaliciousThreshold != 60 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadFile_TOML(t *testing.T) {
+	p := writeConfig(t, "urwarden.toml", `
+suspicious_tlds = ["zip", "mov"]
+
+[thresholds]
+suspicious = 40
+
+[rules.suspicious_tld]
+enabled = false
+
+[rules.path_has_login_like]
+weight = 15
+
+[server]
+shutdown_timeout = "3s"
+
+[[blocklists]]
+name = "phishing"
+path = "p.txt"
+weight = 80
+
+[[blocklists]]
+path = "lists/ads.txt"
+`)
+	cfg := config.Default()
+	if err := cfg.LoadFile(p); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if len(cfg.Blocklists) != 2 || cfg.Blocklists[0].Weight != 80 || cfg.Blocklists[1].Name != "ads" {
+		t.Errorf("blocklists = %+v", cfg.Blocklists)
+	}
+	if cfg.SuspiciousThreshold != 40 || cfg.MaliciousThreshold != 70 {
+		t.Errorf("thresholds = %d/%d, want 40/70", cfg.SuspiciousThreshold, cfg.MaliciousThreshold)
+	}
+	if len(cfg.DisabledRules) != 1 || cfg.RuleWeights["path_has_login_like"] != 15 {
+		t.Errorf("rules = %v / %v", cfg.DisabledRules, cfg.RuleWeights)
+	}
+	if strings.Join(cfg.SuspiciousTLDs, ",") != "zip,mov" || cfg.ShutdownTimeout != 3*time.Second {
+		t.Errorf("tlds = %v, shutdown timeout = %s", cfg.SuspiciousTLDs, cfg.ShutdownTimeout)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+		want []string
+	}{
+		{
+			name: "threshold order",
+			file: "c.yaml",
+			body: "thresholds:\n  suspicious: 80\n  malicious: 70\n",
+			want: []string{"c.yaml:2: thresholds.suspicious: 80 must be below thresholds.malicious (70)"},
+		},
+		{
+			name: "unknown key",
+			file: "c.yaml",
+			body: "verbose: true\nblocklsit: x.txt\n",
+			want: []string{"c.yaml:2: field blocklsit not found"},
+		},
+		{
+			name: "type mismatch",
+			file: "c.yaml",
+			body: "thresholds:\n  malicious: high\n",
+			want: []string{"c.yaml:2: cannot unmarshal"},
+		},
+		{
+			name: "syntax error",
+			file: "c.json",
+			body: "{\n  \"verbose\": true,\n  \"allowlist\": [\n}\n",
+			want: []string{"c.json:"},
+		},
+		{
+			name: "several problems",
+			file: "c.yaml",
+			body: "rules:\n  no_such_rule:\n    weight: 1\n  suspicious_tld:\n    weight: -5\nallowlist_policy: maybe\nblocklists:\n  - name: a\n",
+			want: []string{
+				"c.yaml:2: rules.no_such_rule: unknown rule",
+				"c.yaml:5: rules.suspicious_tld.weight: must not be negative",
+				"c.yaml:6: allowlist_policy: must be",
+				"c.yaml:8: blocklists.0: path is required",
+			},
+		},
+		{
+			name: "list public key",
+			file: "c.yaml",
+			body: "verify_checksums: true\nlist_public_keys:\n  - keys/missing.pub\n",
+			want: []string{`c.yaml:3: list_public_keys.0: "keys/missing.pub" is neither a minisign public key nor a key file`},
+		},
+		{
+			name: "toml unknown key",
+			file: "c.toml",
+			body: "verbose = true\n\n[thresholds]\nsuspicous = 30\n",
+			want: []string{"c.toml:4: field thresholds.suspicous not found"},
+		},
+		{
+			name: "toml type mismatch",
+			file: "c.toml",
+			body: "[thresholds]\nmalicious = \"high\"\n",
+			want: []string{"c.toml:2: thresholds.malicious: "},
+		},
+		{
+			name: "toml validation",
+			file: "c.toml",
+			body: "[[blocklists]]\npath = \"a.txt\"\n\n[[blocklists]]\nweight = -1\n",
+			want: []string{"c.toml:4: blocklists.1: path is required", "c.toml:5: blocklists.1.weight: must not be negative"},
+		},
+		{
+			name: "unsupported format",
+			file: "c.ini",
+			body: "verbose = true\n",
+			want: []string{`unsupported format ".ini"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeConfig(t, tt.file, tt.body)
+			err := config.Default().LoadFile(p)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			msg := strings.ReplaceAll(err.Error(), filepath.Dir(p)+string(filepath.Separator), "")
+			for _, w := range tt.want {
+				if !strings.Contains(msg, w) {
+					t.Errorf("error %q must contain %q", msg, w)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config must be valid: %v", err)
+	}
+
+	cfg.SuspiciousThreshold = 70
+	cfg.AllowlistPolicy = "ignore"
+	cfg.FailOn = "benign"
+	cfg.OnlyLabels = []string{"malicous"}
+	cfg.MinScore = -1
+	cfg.LogFormat = "xml"
+	cfg.ListPublicKeys = []string{"RWQnot-a-key"}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, w := range []string{"must be below malicious threshold", `allowlist policy "ignore"`, `fail-on label "benign"`, `label "malicous"`, "minimum score (-1)", `log format "xml"`, "list public keys"} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q must contain %q", err, w)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOML files use the same keys as YAML ones:
+//
+//	blocklist = "data/blocklist.txt"
+//	suspicious_tlds = ["zip", "mov"]
+//
+//	[thresholds]
+//	suspicious = 40
+//
+//	[rules.path_has_login_like]
+//	weight = 15
+//
+//	[[blocklists]]
+//	name = "phishing"
+//	path = "data/phishing.txt"
+//
+// The TOML decoder does not expose key positions, so the lines used in error
+// messages come from a scan of table headers and "key =" lines (tomlLines).
+
+// decodeTOML strictly decodes a TOML file into fc
+func decodeTOML(path string, data []byte, fc *fileConfig) (lineFunc, error) {
+	lines := tomlLines(string(data))
+	line := func(keys ...string) int {
+		for n := len(keys); n > 0; n-- {
+			if l, ok := lines[strings.Join(keys[:n], ".")]; ok {
+				return l
+			}
+		}
+		return 1
+	}
+
+	md, err := toml.Decode(string(data), fc)
+	if err != nil {
+		return nil, tomlError(path, err)
+	}
+
+	// Unknown keys, reported like the YAML decoder does
+	undecoded := md.Undecoded()
+	if len(undecoded) == 0 {
+		return line, nil
+	}
+	slices.SortStableFunc(undecoded, func(a, b toml.Key) int { return line(a...) - line(b...) })
+	errs := make([]error, 0, len(undecoded))
+	for _, k := range undecoded {
+		errs = append(errs, fmt.Errorf("%s:%d: field %s not found", path, line(k...), k))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// decodeErrorPattern matches type errors: `toml: line 3 (last key "a.b"): msg`
+var decodeErrorPattern = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+// tomlError rewrites TOML syntax and type errors as "path:line: message"
+func tomlError(path string, err error) error {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		msg := parseErr.Message
+		if parseErr.LastKey != "" {
+			msg = parseErr.LastKey + ": " + msg
+		}
+		return fmt.Errorf("%s:%d: %s", path, parseErr.Position.Line, msg)
+	}
+	if m := decodeErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		return fmt.Errorf("%s:%s: %s: %s", path, m[1], m[2], m[3])
+	}
+	return fmt.Errorf("%s: %s", path, strings.TrimPrefix(err.Error(), "toml: "))
+}
+
+var (
+	// [table] and [[array.of.tables]] headers
+	tomlHeader = regexp.MustCompile(`^\[(\[?)\s*([A-Za-z0-9_.\-" ]+?)\s*\]\]?$`)
+	// key = value, with bare or dotted keys
+	tomlKey = regexp.MustCompile(`^([A-Za-z0-9_\-.]+)\s*=`)
+)
+
+// tomlLines maps dotted key paths ("thresholds.suspicious",
+// "blocklists.0.path") to the line defining them. Keys inside inline tables
+// and multi-line values are not indexed; callers fall back to the enclosing key.
+func tomlLines(data string) map[string]int {
+	lines := make(map[string]int)
+	counts := make(map[string]int) // entries seen per array of tables
+	prefix := ""
+	for i, text := range strings.Split(data, "\n") {
+		text = strings.TrimSpace(text)
+		n := i + 1
+		header, _, _ := strings.Cut(text, "#")
+		if m := tomlHeader.FindStringSubmatch(strings.TrimSpace(header)); m != nil {
+			name := strings.ReplaceAll(m[2], `"`, "")
+			if m[1] == "[" {
+				if _, ok := lines[name]; !ok {
+					lines[name] = n
+				}
+				idx := counts[name]
+				counts[name]++
+				name += "." + strconv.Itoa(idx)
+			}
+			prefix = name + "."
+			lines[name] = n
+			continue
+		}
+		if m := tomlKey.FindStringSubmatch(text); m != nil {
+			key := prefix + m[1]
+			lines[key] = n
+			// Dotted keys ("rules.x.weight = 1") also locate their parents
+			for j := strings.LastIndexByte(key, '.'); j > len(prefix); j = strings.LastIndexByte(key[:j], '.') {
+				if _, ok := lines[key[:j]]; !ok {
+					lines[key[:j]] = n
+				}
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// maxMIMEDepth bounds nesting of multipart and message/rfc822 parts
+const maxMIMEDepth = 16
+
+// header is satisfied by mail.Header and textproto.MIMEHeader
+type header interface {
+	Get(key string) string
+}
+
+// extractEML parses an RFC 822 message and walks its MIME tree. Parts are
+// numbered like IMAP sections: "1" for a single-part body, "1", "2", "2.1"
+// for multipart children. Lines are counted within each decoded part.
+func extractEML(r io.Reader, fn func(Found) error) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return fmt.Errorf("read email: %w", err)
+	}
+	return walkPart(msg.Header, msg.Body, "", 0, fn)
+}
+
+func walkPart(h header, body io.Reader, path string, depth int, fn func(Found) error) error {
+	if depth > maxMIMEDepth {
+		return fmt.Errorf("email part %s: MIME nesting deeper than %d", path, maxMIMEDepth)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain" // RFC 2045 default
+	}
+	body = decodeTransfer(h.Get("Content-Transfer-Encoding"), body)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 1; ; i++ {
+			p, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("email part %s: %w", childPath(path, i), err)
+			}
+			if err := walkPart(p.Header, p, childPath(path, i), depth+1, fn); err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		inner, err := mail.ReadMessage(body)
+		if err != nil {
+			return fmt.Errorf("email part %s: %w", path, err)
+		}
+		return walkPart(inner.Header, inner.Body, path, depth+1, fn)
+	}
+
+	if path == "" {
+		path = "1"
+	}
+	switch {
+	case mediaType == "text/html":
+		return extractHTML(body, mediaType, path, fn)
+	case strings.HasPrefix(mediaType, "text/"):
+		return extractText(body, mediaType, path, fn)
+	default:
+		// Attachments (images, archives, ...) are not scanned
+		return nil
+	}
+}
+
+// childPath returns the section number of the i-th child of path
+func childPath(path string, i int) string {
+	if path == "" {
+		return strconv.Itoa(i)
+	}
+	return path + "." + strconv.Itoa(i)
+}
+
+// decodeTransfer undoes the Content-Transfer-Encoding of a part
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r) // skips line breaks itself
+	default:
+		return r
+	}
+}
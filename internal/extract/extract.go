@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// This package finds URLs embedded in free text, RFC 822 email messages and
+// HTML documents. Every URL is reported together with where it was found so
+// results can be traced back to the source.
+
+// Format selects how input is interpreted
+type Format string
+
+const (
+	FormatAuto Format = "auto" // detect from file name and content
+	FormatText Format = "text"
+	FormatEML  Format = "eml"
+	FormatHTML Format = "html"
+)
+
+// ParseFormat validates a format name given on the command line
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatAuto, FormatText, FormatEML, FormatHTML:
+		return f, nil
+	case "":
+		return FormatAuto, nil
+	default:
+		return "", fmt.Errorf("unknown extract format %q (use auto, text, eml or html)", s)
+	}
+}
+
+// Found is one URL located in the input
+type Found struct {
+	URL     string
+	Line    int    // 1-based line within the source, or within the MIME part for email
+	Part    string // MIME part number for email, e.g. "1" or "2.1"; empty otherwise
+	Context string // where the URL appeared, e.g. "text" or "html a[href]"
+}
+
+// sniffSize is how much input is inspected to detect the format
+const sniffSize = 4096
+
+// maxLineLength bounds lines in text input
+const maxLineLength = 1024 * 1024
+
+// Extract reads r in the given format and calls fn for every URL found, in
+// input order. name is used to detect the format from its extension when
+// format is FormatAuto. Extraction stops at the first error returned by fn.
+func Extract(r io.Reader, name string, format Format, fn func(Found) error) error {
+	br := bufio.NewReaderSize(r, sniffSize)
+	if format == FormatAuto || format == "" {
+		head, _ := br.Peek(sniffSize)
+		format = Detect(name, head)
+	}
+
+	switch format {
+	case FormatEML:
+		return extractEML(br, fn)
+	case FormatHTML:
+		return extractHTML(br, "html", "", fn)
+	default:
+		return extractText(br, "text", "", fn)
+	}
+}
+
+// Detect guesses the input format from the file extension, then from the
+// first bytes of the content
+func Detect(name string, head []byte) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".eml":
+		return FormatEML
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".txt", ".log":
+		return FormatText
+	}
+
+	if looksLikeEmail(head) {
+		return FormatEML
+	}
+	lower := bytes.ToLower(head)
+	for _, marker := range []string{"<!doctype html", "<html", "<body", "<a ", "<form", "<meta "} {
+		if bytes.Contains(lower, []byte(marker)) {
+			return FormatHTML
+		}
+	}
+	return FormatText
+}
+
+// looksLikeEmail reports whether head starts with an RFC 822 header block
+// containing at least two headers that real messages carry
+func looksLikeEmail(head []byte) bool {
+	known := 0
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue // folded header
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(name, " \t") {
+			return false
+		}
+		switch strings.ToLower(name) {
+		case "from", "to", "subject", "date", "received", "message-id", "mime-version", "return-path":
+			known++
+		}
+	}
+	return known >= 2
+}
+
+// urlPattern matches http(s) URLs and www. hosts in free text, including
+// defanged ones (hxxps://, https[:]//) which the scanner refangs
+var urlPattern = regexp.MustCompile(`(?i)\b(?:h(?:tt|xx)ps?(?:://|\[:\]//|\[://\])|www\.)[^\s<>"'` + "`" + `{}|\\^]+`)
+
+// findURLs returns the URLs in s with trailing punctuation removed and the
+// byte offset at which each one starts
+func findURLs(s string) (urls []string, offsets []int) {
+	for _, loc := range urlPattern.FindAllStringIndex(s, -1) {
+		u := trimTrailing(s[loc[0]:loc[1]])
+		if u == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(u), "www.") {
+			u = "http://" + u
+		}
+		if strings.HasSuffix(strings.ToLower(u), "://") {
+			continue
+		}
+		urls = append(urls, u)
+		offsets = append(offsets, loc[0])
+	}
+	return urls, offsets
+}
+
+// trimTrailing drops sentence punctuation after a URL, and closing brackets
+// that have no opening counterpart inside the URL
+func trimTrailing(u string) string {
+	for u != "" {
+		last := u[len(u)-1]
+		switch last {
+		case '.', ',', ';', ':', '!', '?', '*':
+			u = u[:len(u)-1]
+			continue
+		case ')', ']':
+			open := byte('(')
+			if last == ']' {
+				open = '['
+			}
+			if strings.Count(u, string(open)) < strings.Count(u, string(last)) {
+				u = u[:len(u)-1]
+				continue
+			}
+		}
+		return u
+	}
+	return u
+}
+
+// extractText scans r line by line for URLs
+func extractText(r io.Reader, context, part string, fn func(Found) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	line := 0
+	for sc.Scan() {
+		line++
+		urls, _ := findURLs(sc.Text())
+		for _, u := range urls {
+			if err := fn(Found{URL: u, Line: line, Part: part, Context: context}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read text line %d: %w", line+1, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package extract_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/extract"
+)
+
+func collect(t *testing.T, input, name string, format extract.Format) []extract.Found {
+	t.Helper()
+	var got []extract.Found
+	err := extract.Extract(strings.NewReader(input), name, format, func(f extract.Found) error {
+		got = append(got, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	return got
+}
+
+func assertFound(t *testing.T, got, want []extract.Found) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d URLs %+v, want %d %+v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("found[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtract_Text(t *testing.T) {
+	input := "Hi team,\n" +
+		"the link (https://evil.example.com/a_(b)) was reported.\n" +
+		"Also see www.example.org/x, and <http://mail.example.net/u>!\n" +
+		"Not URLs: ftp://files.example.com http:// example.com\n" +
+		"IOC: hxxps[:]//evil[.]example[.]xyz/a\n"
+	got := collect(t, input, "ticket.txt", extract.FormatAuto)
+	assertFound(t, got, []extract.Found{
+		{URL: "https://evil.example.com/a_(b)", Line: 2, Context: "text"},
+		{URL: "http://www.example.org/x", Line: 3, Context: "text"},
+		{URL: "http://mail.example.net/u", Line: 3, Context: "text"},
+		{URL: "hxxps[:]//evil[.]example[.]xyz/a", Line: 5, Context: "text"},
+	})
+}
+
+func TestExtract_HTML(t *testing.T) {
+	input := `<!DOCTYPE html>
+<html><head>
+<meta http-equiv="refresh" content="5; URL='https://redirect.example.com/next'">
+<link rel="stylesheet" href="/local.css">
+</head>
+<body>
+<a href="https://bad.example.com/login?a=1&amp;b=2">Sign in</a>
+<a href="mailto:x@example.com">mail</a> <a href="#top">top</a>
+<form action="//collect.example.net/post"><input type="submit"></form>
+<img
+  src="https://cdn.example.com/logo.png">
+<p>Visit https://text.example.org today</p>
+<script>location = "https://script.example.xyz/";</script>
+</body></html>
+`
+	got := collect(t, input, "", extract.FormatAuto)
+	assertFound(t, got, []extract.Found{
+		{URL: "https://redirect.example.com/next", Line: 3, Context: "html meta[refresh]"},
+		{URL: "https://bad.example.com/login?a=1&b=2", Line: 7, Context: "html a[href]"},
+		{URL: "http://collect.example.net/post", Line: 9, Context: "html form[action]"},
+		{URL: "https://cdn.example.com/logo.png", Line: 10, Context: "html img[src]"},
+		{URL: "https://text.example.org", Line: 12, Context: "html text"},
+		{URL: "https://script.example.xyz/", Line: 13, Context: "html script"},
+	})
+}
+
+func TestExtract_EML(t *testing.T) {
+	data, err := os.ReadFile("testdata/phish.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Content sniffing must recognise the message without the .eml extension
+	got := collect(t, string(data), "-", extract.FormatAuto)
+	assertFound(t, got, []extract.Found{
+		{URL: "https://paypa1-secure.example.xyz/login?id=1", Line: 2, Part: "1.1", Context: "text/plain"},
+		{URL: "http://news.example.com/u", Line: 3, Part: "1.1", Context: "text/plain"},
+		{URL: "https://paypa1-secure.example.xyz/login?id=1", Line: 3, Part: "1.2", Context: "text/html a[href]"},
+		{URL: "http://www.forwarded.example.net/offer", Line: 1, Part: "2", Context: "text/plain"},
+	})
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want extract.Format
+	}{
+		{"mail.eml", "", extract.FormatEML},
+		{"page.HTM", "", extract.FormatHTML},
+		{"notes.txt", "<html>", extract.FormatText},
+		{"-", "From: a@example.com\nSubject: hi\n\nbody", extract.FormatEML},
+		{"-", "Note: this is text\nhttps://example.com\n", extract.FormatText},
+		{"-", "<div><a href=\"https://x.example\">x</a></div>", extract.FormatHTML},
+	}
+	for _, tt := range tests {
+		if got := extract.Detect(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %s, want %s", tt.name, tt.head, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := extract.ParseFormat("EML"); err != nil || f != extract.FormatEML {
+		t.Fatalf("ParseFormat(EML) = %v, %v", f, err)
+	}
+	if _, err := extract.ParseFormat("pdf"); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,149 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// urlAttrs lists the attributes that carry a URL, by element
+var urlAttrs = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"link":   {"href"},
+	"base":   {"href"},
+	"img":    {"src"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"frame":  {"src"},
+	"embed":  {"src"},
+	"source": {"src"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"form":   {"action"},
+	"button": {"formaction"},
+	"input":  {"formaction", "src"},
+	"object": {"data"},
+}
+
+// extractHTML tokenizes an HTML document and reports URLs from link-bearing
+// attributes, meta refresh redirects and text content. prefix is prepended
+// to every context.
+func extractHTML(r io.Reader, prefix, part string, fn func(Found) error) error {
+	z := html.NewTokenizer(r)
+	line := 1
+	inScript := false
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return fmt.Errorf("read html: %w", err)
+			}
+			return nil
+		}
+		start := line
+		line += bytes.Count(z.Raw(), []byte{'\n'})
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data == "script" || tok.Data == "style" {
+				inScript = tt == html.StartTagToken
+			}
+			for _, f := range tagURLs(tok) {
+				f.Line, f.Part = start, part
+				f.Context = prefix + " " + f.Context
+				if err := fn(f); err != nil {
+					return err
+				}
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "script" || string(name) == "style" {
+				inScript = false
+			}
+		case html.TextToken:
+			context := prefix + " text"
+			if inScript {
+				context = prefix + " script"
+			}
+			text := string(z.Text())
+			urls, offsets := findURLs(text)
+			for i, u := range urls {
+				f := Found{
+					URL:     u,
+					Line:    start + strings.Count(text[:offsets[i]], "\n"),
+					Part:    part,
+					Context: context,
+				}
+				if err := fn(f); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// tagURLs returns the absolute http(s) URLs carried by a start tag
+func tagURLs(tok html.Token) []Found {
+	var out []Found
+	attr := func(key string) (string, bool) {
+		for _, a := range tok.Attr {
+			if a.Namespace == "" && a.Key == key {
+				return a.Val, true
+			}
+		}
+		return "", false
+	}
+
+	for _, key := range urlAttrs[tok.Data] {
+		if val, ok := attr(key); ok {
+			if u := absoluteURL(val); u != "" {
+				out = append(out, Found{URL: u, Context: tok.Data + "[" + key + "]"})
+			}
+		}
+	}
+
+	// <meta http-equiv="refresh" content="0; url=https://...">
+	if tok.Data == "meta" {
+		if equiv, _ := attr("http-equiv"); strings.EqualFold(equiv, "refresh") {
+			content, _ := attr("content")
+			if u := absoluteURL(refreshURL(content)); u != "" {
+				out = append(out, Found{URL: u, Context: "meta[refresh]"})
+			}
+		}
+	}
+	return out
+}
+
+// refreshURL extracts the target of a meta refresh content value
+func refreshURL(content string) string {
+	_, rest, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 4 && strings.EqualFold(rest[:3], "url") {
+		rest = strings.TrimSpace(rest[3:])
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+	}
+	return strings.Trim(rest, `'"`)
+}
+
+// absoluteURL keeps http(s) and protocol-relative URLs. Relative links,
+// fragments, mailto: and javascript: URLs carry no host and are dropped.
+func absoluteURL(v string) string {
+	v = strings.TrimSpace(v)
+	lower := strings.ToLower(v)
+	switch {
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return v
+	case strings.HasPrefix(v, "//") && len(v) > 2:
+		return "http:" + v
+	}
+	return ""
+}
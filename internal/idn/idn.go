@@ -0,0 +1,190 @@
+package idn
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// This package handles internationalized domain names (IDN): conversion
+// between Unicode and ASCII (punycode, "xn--") forms, and the script and
+// confusable-character checks used to spot homograph hosts such as
+// "аpple.com" written with a Cyrillic "а".
+
+// profile maps hosts the way browsers do for lookup (case folding, NFC,
+// width mapping) but tolerates underscores and other characters that are
+// common in real-world hostnames and blocklists.
+var profile = idna.New(
+	idna.MapForLookup(),
+	idna.StrictDomainName(false),
+	idna.Transitional(false),
+)
+
+// ToASCII returns the ASCII (punycode) form of host. ASCII input is returned
+// unchanged.
+func ToASCII(host string) (string, error) {
+	if IsASCII(host) {
+		return host, nil
+	}
+	ascii, err := profile.ToASCII(host)
+	if err != nil {
+		// Fall back to plain punycode encoding for labels the lookup
+		// profile rejects, so the host can still be scored
+		return idna.Punycode.ToASCII(host)
+	}
+	return ascii, nil
+}
+
+// ToUnicode returns the Unicode form of host, decoding "xn--" labels.
+// Labels that fail to decode are kept as-is.
+func ToUnicode(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, "xn--") {
+			continue
+		}
+		if u, err := idna.Punycode.ToUnicode(label); err == nil {
+			labels[i] = u
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// IsASCII reports whether s contains only ASCII characters
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// scripts checked by Scripts, in reporting order
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Armenian", unicode.Armenian},
+	{"Georgian", unicode.Georgian},
+	{"Hebrew", unicode.Hebrew},
+	{"Arabic", unicode.Arabic},
+	{"Thai", unicode.Thai},
+	{"Cherokee", unicode.Cherokee},
+	{"Han", unicode.Han},
+	{"Hiragana", unicode.Hiragana},
+	{"Katakana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Bopomofo", unicode.Bopomofo},
+}
+
+// Scripts returns the sorted names of the scripts used by the letters in
+// label. Digits, hyphens and other script-neutral characters are ignored;
+// letters from scripts not listed above are reported as "Other".
+func Scripts(label string) []string {
+	seen := make(map[string]struct{})
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		name := "Other"
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				name = s.name
+				break
+			}
+		}
+		seen[name] = struct{}{}
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// allowedMixes are script combinations that legitimately appear together in
+// one label (UTS #39 "highly restrictive" profile)
+var allowedMixes = [][]string{
+	{"Han", "Hiragana", "Katakana", "Latin"},
+	{"Han", "Hangul", "Latin"},
+	{"Bopomofo", "Han", "Latin"},
+}
+
+// MixedScript reports whether label combines scripts that do not normally
+// appear together, e.g. Latin and Cyrillic. It returns the scripts found.
+func MixedScript(label string) (bool, []string) {
+	found := Scripts(label)
+	if len(found) < 2 {
+		return false, found
+	}
+	for _, allowed := range allowedMixes {
+		if subset(found, allowed) {
+			return false, found
+		}
+	}
+	return true, found
+}
+
+func subset(items, set []string) bool {
+	for _, item := range items {
+		i := sort.SearchStrings(set, item)
+		if i == len(set) || set[i] != item {
+			return false
+		}
+	}
+	return true
+}
+
+// confusables maps characters to the ASCII letter they are commonly
+// mistaken for. It is a curated subset of the Unicode confusables data
+// (UTS #39) covering the Cyrillic, Greek, Armenian and Latin look-alikes
+// seen in phishing domains.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'к': 'k', 'ӏ': 'l', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r',
+	'с': 'c', 'ѕ': 's', 'т': 't', 'џ': 'u', 'у': 'y', 'ԝ': 'w', 'х': 'x', 'ԁ': 'd',
+	'ɡ': 'g', 'ԍ': 'g', 'ү': 'y', 'ꙇ': 'i',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ϲ': 'c', 'ϳ': 'j', 'ω': 'w', 'γ': 'y',
+	// Armenian
+	'ա': 'w', 'ց': 'g', 'հ': 'h', 'ո': 'n', 'ս': 'u', 'օ': 'o', 'զ': 'q',
+	// Latin look-alikes
+	'ı': 'i', 'ɩ': 'i', 'ɑ': 'a', 'ɒ': 'a', 'ȷ': 'j', 'ʏ': 'y', 'ɴ': 'n', 'ʀ': 'r',
+	'ꞵ': 'b', 'ƅ': 'b', 'ɵ': 'o', 'ø': 'o', 'ł': 'l', 'ƚ': 'l', 'đ': 'd', 'ħ': 'h',
+	// Digits and symbols that read as letters
+	'0': 'o', '1': 'l',
+}
+
+// Skeleton maps s to a canonical form in which confusable characters
+// compare equal: "аpple.com" (Cyrillic а) and "apple.com" share the
+// skeleton "apple.com". Diacritics are stripped after NFD decomposition, so
+// "äpple" also maps to "apple".
+func Skeleton(s string) string {
+	s = strings.ToLower(norm.NFD.String(s))
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package idn_test
+
+import (
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/idn"
+)
+
+func TestToASCIIAndBack(t *testing.T) {
+	tests := []struct {
+		unicode string
+		ascii   string
+	}{
+		{"аpple.com", "xn--pple-43d.com"},
+		{"münchen.de", "xn--mnchen-3ya.de"},
+		{"example.com", "example.com"},
+		{"under_score.example.com", "under_score.example.com"},
+	}
+	for _, tt := range tests {
+		got, err := idn.ToASCII(tt.unicode)
+		if err != nil {
+			t.Fatalf("ToASCII(%q) error = %v", tt.unicode, err)
+		}
+		if got != tt.ascii {
+			t.Errorf("ToASCII(%q) = %q, want %q", tt.unicode, got, tt.ascii)
+		}
+		if back := idn.ToUnicode(got); back != tt.unicode {
+			t.Errorf("ToUnicode(%q) = %q, want %q", got, back, tt.unicode)
+		}
+	}
+
+	// Invalid punycode is kept as-is
+	if got := idn.ToUnicode("xn--zz-invalid-.com"); got != "xn--zz-invalid-.com" {
+		t.Errorf("ToUnicode(invalid) = %q", got)
+	}
+}
+
+func TestMixedScript(t *testing.T) {
+	tests := []struct {
+		label string
+		mixed bool
+	}{
+		{"apple", false},
+		{"аpple", true}, // Cyrillic а + Latin
+		{"рау", false},  // all Cyrillic
+		{"αpple", true}, // Greek α + Latin
+		{"日本ドメイン", false},
+		{"sonyストア", false}, // Latin + Katakana is allowed
+		{"123-abc", false},
+	}
+	for _, tt := range tests {
+		mixed, scripts := idn.MixedScript(tt.label)
+		if mixed != tt.mixed {
+			t.Errorf("MixedScript(%q) = %v (%v), want %v", tt.label, mixed, scripts, tt.mixed)
+		}
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"аpple.com", "apple.com"},
+		{"аррӏе.com", "apple.com"},
+		{"pаypаl.com", "paypal.com"},
+		{"äpple.com", "apple.com"},
+		{"g00gle.com", "google.com"},
+		{"Example.COM", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := idn.Skeleton(tt.in); got != tt.want {
+			t.Errorf("Skeleton(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
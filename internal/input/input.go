@@ -2,63 +2,197 @@ package input
 
 import (
 	"bufio"
+	"container/list"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
 	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/extract"
 	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/model"
 	"github.com/samuraidays/urwarden/internal/utils"
 )
 
+// Item is one URL together with where it was read from
+type Item struct {
+	URL     string
+	Source  string // "args", "-" for STDIN, or the input file path
+	Line    int    // 1-based line number (argument position for args)
+	Part    string // MIME part number (extract mode, email input)
+	Context string // where the URL appeared (extract mode)
+}
+
+// Location returns where the item was found, for reporting in results
+func (it Item) Location() *model.Location {
+	return &model.Location{Source: it.Source, Line: it.Line, Part: it.Part, Context: it.Context}
+}
+
+// SourceArgs is the Item.Source of URLs given on the command line
+const SourceArgs = "args"
+
 // FromArgsOrInput reads URLs from either command line arguments or input file/STDIN
 // - path == "" uses command line arguments
 // - path == "-" reads from STDIN
 // - otherwise reads from the specified file
 // Empty lines and lines starting with # are skipped
 func FromArgsOrInput(args []string, path string, cfg *config.Config) ([]string, error) {
+	var out []string
+	err := Stream(args, path, cfg, func(it Item) error {
+		out = append(out, it.URL)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return utils.Dedupe(out), nil
+}
+
+// Stream calls fn for every URL from the command line arguments or the input
+// file/STDIN (see FromArgsOrInput), in input order and without holding the
+// whole input in memory. When cfg.Dedupe is set, URLs repeating one of the
+// last cfg.DedupeWindow distinct URLs are skipped. Stream stops at the first error
+// returned by fn and returns it.
+func Stream(args []string, path string, cfg *config.Config, fn func(Item) error) error {
+	emit := dedupeFunc(cfg, fn)
+
 	if strings.TrimSpace(path) == "" {
 		// Use command line arguments directly
 		// Example: urwarden https://a https://b
-		return utils.Dedupe(args), nil
+		for i, arg := range args {
+			if err := emit(Item{URL: arg, Source: SourceArgs, Line: i + 1}); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
-	var r io.ReadCloser
-	if path == "-" {
-		// Read from STDIN
-		r = os.Stdin
-	} else {
-		f, err := os.Open(path)
-		if err != nil {
-			return nil, fmt.Errorf("open %s: %w", path, err)
-		}
-		r = f
-		defer func() {
-			if err := r.Close(); err != nil {
-				logger.Debug("failed to close file: %v", err)
-			}
-		}()
+	r, closeFn, err := open(path)
+	if err != nil {
+		return err
 	}
+	defer closeFn()
 
 	sc := bufio.NewScanner(r)
 	// Expand buffer for long lines
 	buf := make([]byte, 0, cfg.BufferSize)
 	sc.Buffer(buf, cfg.MaxLineLength)
 
-	var out []string
+	lineNo, count := 0, 0
 	for sc.Scan() {
+		lineNo++
 		line := strings.TrimSpace(sc.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		out = append(out, line)
+		count++
+		if err := emit(Item{URL: line, Source: path, Line: lineNo}); err != nil {
+			return err
+		}
 	}
 
 	if err := sc.Err(); err != nil {
-		return nil, fmt.Errorf("scan: %w", err)
+		return fmt.Errorf("scan %s line %d: %w", path, lineNo+1, err)
 	}
 
-	logger.Debug("read %d URLs from input", len(out))
-	return utils.Dedupe(out), nil
+	logger.Debug("read input", "path", path, "urls", count)
+	return nil
+}
+
+// Extract is like Stream, but finds URLs anywhere in free text, email
+// messages and HTML instead of expecting one URL per line. Each command line
+// argument is scanned as text. Items carry the line, MIME part and context
+// where the URL was found.
+func Extract(args []string, path string, format extract.Format, cfg *config.Config, fn func(Item) error) error {
+	emit := dedupeFunc(cfg, fn)
+
+	if strings.TrimSpace(path) == "" {
+		for i, arg := range args {
+			err := extract.Extract(strings.NewReader(arg), "", extract.FormatText, func(f extract.Found) error {
+				return emit(Item{URL: f.URL, Source: SourceArgs, Line: i + 1, Context: f.Context})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	r, closeFn, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	count := 0
+	err = extract.Extract(r, path, format, func(f extract.Found) error {
+		count++
+		return emit(Item{URL: f.URL, Source: path, Line: f.Line, Part: f.Part, Context: f.Context})
+	})
+	if err != nil {
+		return fmt.Errorf("extract %s: %w", path, err)
+	}
+
+	logger.Debug("extracted URLs", "path", path, "urls", count)
+	return nil
+}
+
+// open returns STDIN for "-" or the opened file, and a function closing it
+func open(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		// Read from STDIN
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return f, func() {
+		if err := f.Close(); err != nil {
+			logger.Debug("failed to close input file", "path", path, "error", err)
+		}
+	}, nil
+}
+
+// dedupeFunc wraps fn to skip repeated URLs when cfg.Dedupe is set. Only
+// the cfg.DedupeWindow most recently seen URLs are remembered, so memory
+// stays bounded on inputs with millions of distinct URLs.
+func dedupeFunc(cfg *config.Config, fn func(Item) error) func(Item) error {
+	if !cfg.Dedupe || cfg.DedupeWindow <= 0 {
+		return fn
+	}
+	seen := newSeenSet(cfg.DedupeWindow)
+	return func(it Item) error {
+		if seen.add(it.URL) {
+			return nil
+		}
+		return fn(it)
+	}
+}
+
+// seenSet is a fixed-size LRU set of URLs
+type seenSet struct {
+	size  int
+	order *list.List // most recently seen first
+	items map[string]*list.Element
+}
+
+func newSeenSet(size int) *seenSet {
+	return &seenSet{size: size, order: list.New(), items: make(map[string]*list.Element, min(size, 1024))}
+}
+
+// add records url and reports whether it was already in the set
+func (s *seenSet) add(url string) bool {
+	if e, ok := s.items[url]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.items[url] = s.order.PushFront(url)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(string))
+	}
+	return false
 }
@@ -1,11 +1,14 @@
 package input_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/extract"
 	"github.com/samuraidays/urwarden/internal/input"
 )
 
@@ -34,3 +37,103 @@ func TestFromArgsOrInput_File(t *testing.T) {
 		t.Fatalf("want 2, got %d", len(got))
 	}
 }
+
+func TestStream(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "u.txt")
+	_ = os.WriteFile(p, []byte("https://a\n\n# cmt\nhttps://b\nhttps://a\n"), 0o644)
+
+	collect := func(dedupe bool) []input.Item {
+		cfg := config.Default()
+		cfg.Dedupe = dedupe
+		var got []input.Item
+		if err := input.Stream(nil, p, cfg, func(it input.Item) error {
+			got = append(got, it)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := collect(true)
+	want := []input.Item{
+		{URL: "https://a", Source: p, Line: 1},
+		{URL: "https://b", Source: p, Line: 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := collect(false); len(got) != 3 || got[2].Line != 5 {
+		t.Fatalf("without dedupe got %+v", got)
+	}
+}
+
+func TestStream_DedupeWindow(t *testing.T) {
+	cfg := config.Default()
+	cfg.DedupeWindow = 2
+	var got []string
+	args := []string{"https://a", "https://b", "https://a", "https://c", "https://b", "https://a"}
+	if err := input.Stream(args, "", cfg, func(it input.Item) error {
+		got = append(got, it.URL)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// a is seen again before c evicts b; b then falls out of the window
+	want := []string{"https://a", "https://b", "https://c", "https://b", "https://a"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestStream_StopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := input.Stream([]string{"https://a", "https://b", "https://c"}, "", config.Default(), func(it input.Item) error {
+		calls++
+		if it.Line == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || calls != 2 {
+		t.Fatalf("err=%v calls=%d, want stop after 2 calls", err, calls)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "page.html")
+	_ = os.WriteFile(p, []byte("<p>hello</p>\n<a href=\"https://a.example.com\">a</a>\n<a href=\"https://a.example.com\">again</a>\n"), 0o644)
+
+	var got []input.Item
+	err := input.Extract(nil, p, extract.FormatAuto, config.Default(), func(it input.Item) error {
+		got = append(got, it)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := input.Item{URL: "https://a.example.com", Source: p, Line: 2, Context: "html a[href]"}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("got %+v, want [%+v]", got, want)
+	}
+
+	got = nil
+	err = input.Extract([]string{"no links here", "see https://b.example.com."}, "", extract.FormatAuto, config.Default(), func(it input.Item) error {
+		got = append(got, it)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URL != "https://b.example.com" || got[0].Source != input.SourceArgs || got[0].Line != 2 {
+		t.Fatalf("args: got %+v", got)
+	}
+}
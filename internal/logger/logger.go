@@ -1,26 +1,60 @@
 package logger
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
+// This package wraps log/slog with the handful of settings the CLI exposes:
+// a level, an output and a text or JSON encoding. Messages are constant
+// strings; details go into key/value pairs (url, rule, duration, path, ...)
+// so log pipelines can index them.
+
 // Level represents the logging level
-type Level int
+type Level = slog.Level
+
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+	LevelOff   = slog.LevelError + 4 // disables logging
+)
+
+// Format selects how log records are encoded
+type Format string
 
 const (
-	LevelError Level = iota
-	LevelWarn
-	LevelInfo
-	LevelDebug
+	FormatText Format = "text" // key=value pairs, e.g. for terminals
+	FormatJSON Format = "json" // one JSON object per line
 )
 
+// ParseFormat validates a --log-format value
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q (want text or json)", s)
+}
+
+// IDKey is the attribute tying together the entries logged for one URL
+const IDKey = "correlation_id"
+
 // Logger provides structured logging functionality
 type Logger struct {
-	level  Level
+	mu     sync.Mutex
+	level  slog.LevelVar
 	output io.Writer
+	format Format
+	slog   atomic.Pointer[slog.Logger]
 }
 
 // New creates a new logger instance
@@ -28,10 +62,10 @@ func New(level Level, output io.Writer) *Logger {
 	if output == nil {
 		output = os.Stderr
 	}
-	return &Logger{
-		level:  level,
-		output: output,
-	}
+	l := &Logger{output: output, format: FormatText}
+	l.level.Set(level)
+	l.rebuild()
+	return l
 }
 
 // NewDefault creates a logger with default settings
@@ -41,62 +75,171 @@ func NewDefault() *Logger {
 
 // SetLevel changes the logging level
 func (l *Logger) SetLevel(level Level) {
-	l.level = level
+	l.level.Set(level)
 }
 
 // SetOutput changes the output writer
 func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.output = w
+	l.rebuild()
+}
+
+// SetFormat switches between text and JSON records
+func (l *Logger) SetFormat(f Format) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+	l.rebuild()
+}
+
+// rebuild creates the slog handler for the current output and format
+func (l *Logger) rebuild() {
+	opts := &slog.HandlerOptions{Level: &l.level, ReplaceAttr: utcTime}
+	var h slog.Handler
+	if l.format == FormatJSON {
+		h = slog.NewJSONHandler(l.output, opts)
+	} else {
+		h = slog.NewTextHandler(l.output, opts)
+	}
+	l.slog.Store(slog.New(contextHandler{h}))
+}
+
+// logger returns the current slog logger
+func (l *Logger) logger() *slog.Logger {
+	return l.slog.Load()
+}
+
+// Enabled reports whether records at level are written, e.g. to skip
+// computing expensive fields
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level.Level()
 }
 
 // Error logs an error message
-func (l *Logger) Error(format string, args ...interface{}) {
-	l.log(LevelError, "ERROR", format, args...)
+func (l *Logger) Error(msg string, args ...any) {
+	l.logger().Error(msg, args...)
 }
 
 // Warn logs a warning message
-func (l *Logger) Warn(format string, args ...interface{}) {
-	l.log(LevelWarn, "WARN", format, args...)
+func (l *Logger) Warn(msg string, args ...any) {
+	l.logger().Warn(msg, args...)
 }
 
 // Info logs an info message
-func (l *Logger) Info(format string, args ...interface{}) {
-	l.log(LevelInfo, "INFO", format, args...)
+func (l *Logger) Info(msg string, args ...any) {
+	l.logger().Info(msg, args...)
 }
 
 // Debug logs a debug message
-func (l *Logger) Debug(format string, args ...interface{}) {
-	l.log(LevelDebug, "DEBUG", format, args...)
+func (l *Logger) Debug(msg string, args ...any) {
+	l.logger().Debug(msg, args...)
+}
+
+// Log logs a message with the fields attached to ctx by With
+func (l *Logger) Log(ctx context.Context, level Level, msg string, args ...any) {
+	l.logger().Log(ctx, level, msg, args...)
+}
+
+// utcTime logs timestamps in UTC, like the result documents
+func utcTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 && a.Value.Kind() == slog.KindTime {
+		a.Value = slog.TimeValue(a.Value.Time().UTC())
+	}
+	return a
+}
+
+// Context fields
+
+type ctxKey struct{}
+
+// With returns a context whose log entries carry the given key/value pairs
+// in addition to those already attached to ctx
+func With(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	attrs = append(attrs[:len(attrs):len(attrs)], slog.Group("", args...).Value.Group()...)
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+// WithID attaches a new correlation ID to ctx unless it already carries one
+func WithID(ctx context.Context) context.Context {
+	if ID(ctx) != "" {
+		return ctx
+	}
+	return With(ctx, IDKey, NewID())
 }
 
-// log is the internal logging method
-func (l *Logger) log(level Level, levelStr, format string, args ...interface{}) {
-	if level > l.level {
-		return
+// ID returns the correlation ID attached to ctx, or ""
+func ID(ctx context.Context) string {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	for _, a := range attrs {
+		if a.Key == IDKey {
+			return a.Value.String()
+		}
 	}
+	return ""
+}
 
-	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")
-	message := fmt.Sprintf(format, args...)
+// NewID returns a random 16-character correlation ID
+func NewID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
 
-	_, _ = fmt.Fprintf(l.output, "[%s] %s: %s\n", timestamp, levelStr, message)
+// contextHandler adds the fields attached by With to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
 
 // Global logger instance
 var Default = NewDefault()
 
 // Convenience functions for global logger
-func Error(format string, args ...interface{}) {
-	Default.Error(format, args...)
+func Error(msg string, args ...any) {
+	Default.Error(msg, args...)
+}
+
+func Warn(msg string, args ...any) {
+	Default.Warn(msg, args...)
+}
+
+func Info(msg string, args ...any) {
+	Default.Info(msg, args...)
+}
+
+func Debug(msg string, args ...any) {
+	Default.Debug(msg, args...)
 }
 
-func Warn(format string, args ...interface{}) {
-	Default.Warn(format, args...)
+// DebugContext logs a debug message with the fields attached to ctx
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	Default.Log(ctx, LevelDebug, msg, args...)
 }
 
-func Info(format string, args ...interface{}) {
-	Default.Info(format, args...)
+// WarnContext logs a warning with the fields attached to ctx
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	Default.Log(ctx, LevelWarn, msg, args...)
 }
 
-func Debug(format string, args ...interface{}) {
-	Default.Debug(format, args...)
+// Enabled reports whether the global logger writes records at level
+func Enabled(level Level) bool {
+	return Default.Enabled(level)
 }
@@ -0,0 +1,77 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/logger"
+)
+
+func TestLogger_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(logger.LevelDebug, &buf)
+	l.SetFormat(logger.FormatJSON)
+
+	ctx := logger.With(logger.WithID(context.Background()), "line", 3)
+	id := logger.ID(ctx)
+	if len(id) != 16 {
+		t.Fatalf("ID = %q, want 16 hex characters", id)
+	}
+	if again := logger.WithID(ctx); logger.ID(again) != id {
+		t.Errorf("WithID must keep an existing ID")
+	}
+
+	l.Log(ctx, logger.LevelDebug, "scored", "url", "https://example.com", "score", 80)
+	l.Log(ctx, logger.LevelDebug, "rule matched", "rule", "blocklist_hit")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if rec[logger.IDKey] != id || rec["line"] != float64(3) {
+			t.Errorf("record %s must carry %s=%s and line=3", line, logger.IDKey, id)
+		}
+		if ts, _ := rec["time"].(string); !strings.HasSuffix(ts, "Z") {
+			t.Errorf("time %q must be UTC", ts)
+		}
+	}
+	if !strings.Contains(lines[0], `"msg":"scored","url":"https://example.com","score":80`) {
+		t.Errorf("unexpected record: %s", lines[0])
+	}
+}
+
+func TestLogger_Level(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(logger.LevelInfo, &buf)
+	l.Debug("hidden")
+	l.Info("shown", "key", "value")
+	if got := buf.String(); strings.Contains(got, "hidden") || !strings.Contains(got, `level=INFO msg=shown key=value`) {
+		t.Errorf("output = %q", got)
+	}
+
+	buf.Reset()
+	l.SetLevel(logger.LevelOff)
+	l.Error("hidden")
+	if buf.Len() != 0 || l.Enabled(logger.LevelError) {
+		t.Errorf("LevelOff must disable logging, got %q", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"text", "json"} {
+		if f, err := logger.ParseFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+	if _, err := logger.ParseFormat("xml"); err == nil {
+		t.Errorf("ParseFormat(xml) must fail")
+	}
+}
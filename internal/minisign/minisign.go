@@ -0,0 +1,224 @@
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// This package verifies detached signatures in the minisign format
+// (https://jedisct1.github.io/minisign/), which is what blocklist publishers
+// and `minisign -S` produce: Ed25519 over the file (legacy "Ed") or over its
+// BLAKE2b-512 hash ("ED", the default since minisign 0.8), plus a second
+// signature covering the trusted comment.
+//
+// A public key looks like
+//
+//	untrusted comment: minisign public key 3D2A1B7C9E8F6054
+//	RWRUYI+efBsqPTd...
+//
+// and either the whole file or just its second line is accepted.
+
+const (
+	untrustedPrefix = "untrusted comment:"
+	trustedPrefix   = "trusted comment: "
+)
+
+// Signature algorithms
+var (
+	algLegacy  = [2]byte{'E', 'd'} // Ed25519 over the message
+	algPrehash = [2]byte{'E', 'D'} // Ed25519 over BLAKE2b-512(message)
+)
+
+var (
+	errNotAKey          = errors.New("not a minisign public key")
+	errNotASig          = errors.New("not a minisign signature")
+	errNoTrustedComment = errors.New("missing trusted comment")
+)
+
+// Verification errors
+var (
+	ErrUnknownKey       = errors.New("signed by an untrusted key")
+	ErrInvalidSignature = errors.New("signature does not match")
+)
+
+// KeyID identifies the key pair that made a signature
+type KeyID [8]byte
+
+// String formats the ID the way minisign prints it
+func (id KeyID) String() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+// PublicKey is a trusted Ed25519 key
+type PublicKey struct {
+	ID  KeyID
+	Key ed25519.PublicKey
+}
+
+// String returns the key in the base64 form used in .pub files and configs
+func (k PublicKey) String() string {
+	b := make([]byte, 0, 42)
+	b = append(b, algLegacy[:]...)
+	b = append(b, k.ID[:]...)
+	b = append(b, k.Key...)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// ParsePublicKey parses a base64 key or the contents of a .pub file
+func ParsePublicKey(s string) (PublicKey, error) {
+	line := lastLine(s)
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || [2]byte(raw[:2]) != algLegacy {
+		return PublicKey{}, errNotAKey
+	}
+	var k PublicKey
+	copy(k.ID[:], raw[2:10])
+	k.Key = ed25519.PublicKey(bytes.Clone(raw[10:]))
+	return k, nil
+}
+
+// ReadPublicKey reads a .pub file
+func ReadPublicKey(path string) (PublicKey, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return PublicKey{}, err
+	}
+	k, err := ParsePublicKey(string(data))
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// LoadPublicKeys resolves configured keys. Each value is either a base64 key
+// or the path of a .pub file.
+func LoadPublicKeys(values []string) ([]PublicKey, error) {
+	keys := make([]PublicKey, 0, len(values))
+	for _, v := range values {
+		k, err := ParsePublicKey(v)
+		if err != nil {
+			if k, err = ReadPublicKey(v); err != nil {
+				if os.IsNotExist(err) {
+					err = fmt.Errorf("%q is neither a minisign public key nor a key file", v)
+				}
+				return nil, err
+			}
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Signature is a parsed .minisig file
+type Signature struct {
+	Algorithm      [2]byte
+	KeyID          KeyID
+	Signature      []byte // over the message (or its hash)
+	TrustedComment string
+	GlobalSig      []byte // over Signature + TrustedComment
+}
+
+// ParseSignature parses the contents of a .minisig file
+func ParseSignature(data []byte) (*Signature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], untrustedPrefix) {
+		return nil, errNotASig
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return nil, errNotASig
+	}
+	comment, ok := strings.CutPrefix(lines[2], trustedPrefix)
+	if !ok {
+		return nil, errNoTrustedComment
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, errNotASig
+	}
+
+	sig := &Signature{
+		Signature:      raw[10:],
+		TrustedComment: comment,
+		GlobalSig:      global,
+	}
+	copy(sig.Algorithm[:], raw[:2])
+	copy(sig.KeyID[:], raw[2:10])
+	if sig.Algorithm != algLegacy && sig.Algorithm != algPrehash {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm[:])
+	}
+	return sig, nil
+}
+
+// Verify checks that sig (a .minisig file) is a valid signature of msg by
+// one of keys
+func Verify(keys []PublicKey, msg, sig []byte) error {
+	s, err := ParseSignature(sig)
+	if err != nil {
+		return err
+	}
+	var key *PublicKey
+	for i := range keys {
+		if keys[i].ID == s.KeyID {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("%w (key ID %s)", ErrUnknownKey, s.KeyID)
+	}
+
+	if s.Algorithm == algPrehash {
+		h := blake2b.Sum512(msg)
+		msg = h[:]
+	}
+	if !ed25519.Verify(key.Key, msg, s.Signature) {
+		return ErrInvalidSignature
+	}
+	// The trusted comment (timestamp, file name) is signed separately
+	if !ed25519.Verify(key.Key, append(bytes.Clone(s.Signature), s.TrustedComment...), s.GlobalSig) {
+		return fmt.Errorf("%w (trusted comment)", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// Sign returns a prehashed .minisig signature of msg. It is meant for
+// tooling and tests; keep real signing keys in minisign itself.
+func Sign(priv ed25519.PrivateKey, id KeyID, msg []byte, trustedComment string) []byte {
+	h := blake2b.Sum512(msg)
+	sig := ed25519.Sign(priv, h[:])
+	global := ed25519.Sign(priv, append(bytes.Clone(sig), trustedComment...))
+
+	raw := make([]byte, 0, 2+8+ed25519.SignatureSize)
+	raw = append(raw, algPrehash[:]...)
+	raw = append(raw, id[:]...)
+	raw = append(raw, sig...)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s signature from urwarden\n", untrustedPrefix)
+	b.WriteString(base64.StdEncoding.EncodeToString(raw) + "\n")
+	b.WriteString(trustedPrefix + trustedComment + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(global) + "\n")
+	return []byte(b.String())
+}
+
+// lastLine returns the last non-empty line that is not a comment
+func lastLine(s string) string {
+	var out string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, untrustedPrefix) {
+			out = line
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package minisign_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/minisign"
+)
+
+// Signatures of "test" made with minisign itself
+const (
+	testKey       = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	testLegacySig = "untrusted comment: signature from minisign secret key\n" +
+		"RWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\n" +
+		"trusted comment: timestamp:1635442742\tfile:test\n" +
+		"0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n"
+	testPrehashSig = "untrusted comment: signature from minisign secret key\n" +
+		"RUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\n" +
+		"trusted comment: timestamp:1635443258\tfile:test\thashed\n" +
+		"/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := minisign.ParsePublicKey("untrusted comment: minisign public key E7620F1842B4E81F\n" + testKey + "\n")
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if got := key.ID.String(); got != "E7620F1842B4E81F" {
+		t.Errorf("key ID = %s, want E7620F1842B4E81F", got)
+	}
+	if key.String() != testKey {
+		t.Errorf("String() = %s, want %s", key, testKey)
+	}
+	keys := []minisign.PublicKey{key}
+
+	tampered := strings.Replace(testPrehashSig, "hashed", "hashed\tx", 1)
+	tests := []struct {
+		name string
+		msg  string
+		sig  string
+		want error
+	}{
+		{"legacy", "test", testLegacySig, nil},
+		{"prehashed", "test", testPrehashSig, nil},
+		{"other message", "test2", testPrehashSig, minisign.ErrInvalidSignature},
+		{"trusted comment changed", "test", tampered, minisign.ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := minisign.Verify(keys, []byte(tt.msg), []byte(tt.sig))
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := minisign.Verify(nil, []byte("test"), []byte(testPrehashSig)); !errors.Is(err, minisign.ErrUnknownKey) {
+		t.Errorf("Verify() without keys = %v, want ErrUnknownKey", err)
+	}
+	if err := minisign.Verify(keys, []byte("test"), []byte("garbage")); err == nil {
+		t.Error("Verify() accepted a malformed signature")
+	}
+}
+
+func TestSign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := minisign.PublicKey{ID: minisign.KeyID{1, 2, 3, 4, 5, 6, 7, 8}, Key: pub}
+	sig := minisign.Sign(priv, key.ID, []byte("bad.example.com\n"), "file:blocklist.txt")
+
+	if err := minisign.Verify([]minisign.PublicKey{key}, []byte("bad.example.com\n"), sig); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	s, err := minisign.ParseSignature(sig)
+	if err != nil {
+		t.Fatalf("ParseSignature: %v", err)
+	}
+	if s.TrustedComment != "file:blocklist.txt" {
+		t.Errorf("TrustedComment = %q", s.TrustedComment)
+	}
+}
+
+func TestLoadPublicKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.pub")
+	if err := os.WriteFile(path, []byte("untrusted comment: minisign public key\n"+testKey+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := minisign.LoadPublicKeys([]string{testKey, path})
+	if err != nil {
+		t.Fatalf("LoadPublicKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != keys[1].ID {
+		t.Errorf("keys = %v", keys)
+	}
+
+	if _, err := minisign.LoadPublicKeys([]string{"RWQnot-a-key"}); err == nil || !strings.Contains(err.Error(), "neither") {
+		t.Errorf("LoadPublicKeys(invalid) = %v", err)
+	}
+}
@@ -3,17 +3,24 @@ package model
 import "time"
 
 type NormalizedURL struct {
-	Scheme string `json:"scheme"`
-	Host   string `json:"host"`
-	TLD    string `json:"tld"`
-	Path   string `json:"path"`
-	Query  string `json:"query"`
+	Scheme            string `json:"scheme"`
+	Host              string `json:"host"`               // ASCII form (IDN labels punycode-encoded)
+	HostUnicode       string `json:"host_unicode"`       // Unicode form ("xn--" labels decoded)
+	TLD               string `json:"tld"`                // last label of the host
+	PublicSuffix      string `json:"public_suffix"`      // eTLD per the Public Suffix List, e.g. co.uk
+	RegistrableDomain string `json:"registrable_domain"` // eTLD+1, e.g. example.co.uk ("" for IPs and bare suffixes)
+	Port              string `json:"port,omitempty"`     // explicit port, "" when the URL has none
+	Path              string `json:"path"`
+	Query             string `json:"query"`
 }
 
 type Reason struct {
-	Rule   string `json:"rule"`   // blocklist_hit | suspicious_tld | path_has_login_like
-	Weight int    `json:"weight"` // 70 | 20 | 10
-	Detail string `json:"detail"` // matched value etc.
+	Rule     string `json:"rule"`               // rule name, e.g. blocklist_hit | suspicious_tld | path_has_login_like
+	Weight   int    `json:"weight"`             // score points contributed by the rule
+	Detail   string `json:"detail"`             // matched value etc.
+	List     string `json:"list,omitempty"`     // blocklist name (blocklist_hit only)
+	Category string `json:"category,omitempty"` // blocklist category, e.g. phishing | malware
+	Entry    string `json:"entry,omitempty"`    // blocklist entry that matched
 }
 
 type Result struct {
@@ -22,5 +29,15 @@ type Result struct {
 	Score      int           `json:"score"`
 	Label      string        `json:"label"` // benign | suspicious | malicious
 	Reasons    []Reason      `json:"reasons"`
+	Refanged   bool          `json:"refanged,omitempty"` // input_url was defanged (hxxp, [.] ...) and has been refanged for scoring
 	Timestamp  time.Time     `json:"timestamp"`
+	Location   *Location     `json:"location,omitempty"` // set for URLs extracted from text, email or HTML
+}
+
+// Location records where an extracted URL was found
+type Location struct {
+	Source  string `json:"source"`            // input file, "-" for STDIN, or "args"
+	Line    int    `json:"line"`              // 1-based line (within the MIME part for email; argument position for args)
+	Part    string `json:"part,omitempty"`    // MIME part number for email, e.g. "2.1"
+	Context string `json:"context,omitempty"` // e.g. text | html a[href] | text/html meta[refresh]
 }
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// csvHeader names the columns written by CSVWriter
+var csvHeader = []string{
+	"input_url", "score", "label", "host", "tld", "registrable_domain", "reasons", "timestamp",
+}
+
+// CSVWriter flattens results into delimited rows (CSV or TSV) with a header
+// row. Quoting follows RFC 4180 for both delimiters, so fields containing the
+// delimiter, quotes or line breaks stay intact.
+type CSVWriter struct {
+	w           *csv.Writer
+	opts        Options
+	wroteHeader bool
+}
+
+// NewCSVWriter returns a writer using comma (',' for CSV, '\t' for TSV) as
+// the field delimiter
+func NewCSVWriter(w io.Writer, comma rune, opts Options) *CSVWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &CSVWriter{w: cw, opts: opts}
+}
+
+// Write appends one row, preceded by the header row on first use
+func (cw *CSVWriter) Write(res model.Result) error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write(csvHeader); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+
+	res = cw.opts.Prepare(res)
+	rules := make([]string, len(res.Reasons))
+	for i, r := range res.Reasons {
+		rules[i] = r.Rule
+	}
+	row := []string{
+		res.InputURL,
+		strconv.Itoa(res.Score),
+		res.Label,
+		res.Normalized.Host,
+		res.Normalized.TLD,
+		res.Normalized.RegistrableDomain,
+		strings.Join(rules, ";"),
+		res.Timestamp.Format(time.RFC3339),
+	}
+	for i := range row {
+		row[i] = neutralizeFormula(row[i])
+	}
+	if err := cw.w.Write(row); err != nil {
+		return err
+	}
+	// Flush per row so long runs stream instead of buffering
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// Close writes the header if no result was written and flushes
+func (cw *CSVWriter) Close() error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write(csvHeader); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// neutralizeFormula prefixes values that spreadsheet applications would
+// evaluate as formulas (attacker-controlled URLs such as "=HYPERLINK(...)")
+// with a single quote
+func neutralizeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
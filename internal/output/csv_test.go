@@ -0,0 +1,124 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/output"
+)
+
+func sampleResult() model.Result {
+	return model.Result{
+		InputURL: `https://bad.example.com/login?a=1,2&q="x"`,
+		Normalized: model.NormalizedURL{
+			Scheme:            "https",
+			Host:              "bad.example.com",
+			TLD:               "com",
+			RegistrableDomain: "example.com",
+		},
+		Score: 80,
+		Label: "malicious",
+		Reasons: []model.Reason{
+			{Rule: "blocklist_hit", Weight: 70, Detail: "bad.example.com"},
+			{Rule: "path_has_login_like", Weight: 10, Detail: "matched: login"},
+		},
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func readRows(t *testing.T, data string, comma rune) [][]string {
+	t.Helper()
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = comma
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid: %v\n%s", err, data)
+	}
+	return rows
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := output.New(output.FormatCSV, &buf, output.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	formula := sampleResult()
+	formula.InputURL = "=HYPERLINK(\"http://evil.example\")"
+	for _, res := range []model.Result{sampleResult(), formula} {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readRows(t, buf.String(), ',')
+	if len(rows) != 3 {
+		t.Fatalf("want header + 2 rows, got %d", len(rows))
+	}
+	if strings.Join(rows[0], ",") != "input_url,score,label,host,tld,registrable_domain,reasons,timestamp" {
+		t.Errorf("header = %v", rows[0])
+	}
+	want := []string{
+		`https://bad.example.com/login?a=1,2&q="x"`, "80", "malicious", "bad.example.com", "com",
+		"example.com", "blocklist_hit;path_has_login_like", "2026-01-02T03:04:05Z",
+	}
+	if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("row = %q, want %q", rows[1], want)
+	}
+	if got := rows[2][0]; got != `'=HYPERLINK("http://evil.example")` {
+		t.Errorf("formula not neutralized: %q", got)
+	}
+}
+
+func TestTSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := output.New(output.FormatTSV, &buf, output.Options{Defang: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := sampleResult()
+	res.InputURL = "https://bad.example.com/a\tb"
+	if err := w.Write(res); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readRows(t, buf.String(), '\t')
+	if len(rows) != 2 || len(rows[1]) != 8 {
+		t.Fatalf("unexpected rows: %q", rows)
+	}
+	if rows[1][0] != "hxxps://bad[.]example[.]com/a\tb" || rows[1][3] != "bad[.]example[.]com" {
+		t.Errorf("defang not applied or tab not preserved: %q", rows[1])
+	}
+}
+
+func TestCSVWriter_EmptyRun(t *testing.T) {
+	var buf bytes.Buffer
+	w := output.NewCSVWriter(&buf, ',', output.Options{})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "input_url,score,") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("empty run must still write the header, got %q", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]output.Format{"": output.FormatJSON, "CSV": output.FormatCSV, " tsv ": output.FormatTSV} {
+		if got, err := output.ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := output.ParseFormat("xml"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
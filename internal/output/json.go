@@ -2,6 +2,7 @@ package output
 
 import (
 	"encoding/json"
+	"io"
 	"os"
 	"time"
 
@@ -18,7 +19,33 @@ func WriteResultJSON(inputURL string, norm model.NormalizedURL, score int, label
 		Reasons:    reasons,
 		Timestamp:  time.Now().UTC(),
 	}
-	enc := json.NewEncoder(os.Stdout)
+	return WriteJSON(os.Stdout, res)
+}
+
+// WriteJSON encodes a result as a single JSON line to w.
+func WriteJSON(w io.Writer, res model.Result) error {
+	return NewJSONWriter(w, Options{}).Write(res)
+}
+
+// JSONWriter writes results as JSON Lines, one object per result.
+type JSONWriter struct {
+	enc  *json.Encoder
+	opts Options
+}
+
+// NewJSONWriter returns a JSON Lines writer applying opts to every result.
+func NewJSONWriter(w io.Writer, opts Options) *JSONWriter {
+	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
-	return enc.Encode(res)
+	return &JSONWriter{enc: enc, opts: opts}
+}
+
+// Write encodes a single result as one JSON line.
+func (jw *JSONWriter) Write(res model.Result) error {
+	return jw.enc.Encode(jw.opts.Prepare(res))
+}
+
+// Close is a no-op; every line is complete once written.
+func (jw *JSONWriter) Close() error {
+	return nil
 }
@@ -2,7 +2,9 @@ package output_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,3 +53,56 @@ func TestWriteResultJSON(t *testing.T) {
 	// タイムスタンプはUTC前提（雛形では time.Now().UTC()）
 	_ = time.Now() // 呼び出し元と同時刻比較はしない（テストが壊れやすくなるため）
 }
+
+func TestJSONWriter_Defang(t *testing.T) {
+	res := model.Result{
+		InputURL: "https://bad.example.com/login",
+		Normalized: model.NormalizedURL{
+			Scheme:            "https",
+			Host:              "bad.example.com",
+			HostUnicode:       "bad.example.com",
+			RegistrableDomain: "example.com",
+			Path:              "/login",
+		},
+		Score: 80,
+		Label: "malicious",
+		Reasons: []model.Reason{
+			{Rule: "blocklist_hit", Weight: 70, Detail: "matched subdomain of example.com", Entry: "example.com"},
+			{Rule: "path_has_login_like", Weight: 10, Detail: "matched: login"},
+		},
+	}
+
+	var buf bytes.Buffer
+	w := output.NewJSONWriter(&buf, output.Options{Defang: true})
+	if err := w.Write(res); err != nil {
+		t.Fatal(err)
+	}
+	benign := res
+	benign.Label = "benign"
+	if err := w.Write(benign); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %d", len(lines))
+	}
+	var got model.Result
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.InputURL != "hxxps://bad[.]example[.]com/login" ||
+		got.Normalized.Host != "bad[.]example[.]com" ||
+		got.Normalized.RegistrableDomain != "example[.]com" ||
+		got.Reasons[0].Detail != "matched subdomain of example[.]com" ||
+		got.Reasons[0].Entry != "example[.]com" ||
+		got.Reasons[1].Detail != "matched: login" {
+		t.Errorf("malicious result not defanged: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"input_url":"https://bad.example.com/login"`) {
+		t.Errorf("benign result must stay clickable: %s", lines[1])
+	}
+	if res.Reasons[0].Entry != "example.com" {
+		t.Errorf("input result was modified")
+	}
+}
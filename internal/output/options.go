@@ -0,0 +1,44 @@
+package output
+
+import (
+	"slices"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/refang"
+)
+
+// Options control how results are rendered. Every writer applies them
+// through Prepare so that all formats agree.
+type Options struct {
+	// Defang makes URLs and domains of suspicious and malicious results
+	// non-clickable (hxxps://evil[.]com) for sharing in chat and tickets
+	Defang bool
+
+	// Color enables ANSI colours in terminal formats (table)
+	Color bool
+}
+
+// Prepare returns the result as it should be written under o.
+// The input result is not modified.
+func (o Options) Prepare(res model.Result) model.Result {
+	if o.Defang && (res.Label == "suspicious" || res.Label == "malicious") {
+		res = defangResult(res)
+	}
+	return res
+}
+
+// defangResult defangs input_url, the host fields of the normalized URL
+// and every URL or domain mentioned in the reasons
+func defangResult(res model.Result) model.Result {
+	res.InputURL = refang.Defang(res.InputURL)
+	res.Normalized.Host = refang.Defang(res.Normalized.Host)
+	res.Normalized.HostUnicode = refang.Defang(res.Normalized.HostUnicode)
+	res.Normalized.RegistrableDomain = refang.Defang(res.Normalized.RegistrableDomain)
+
+	res.Reasons = slices.Clone(res.Reasons)
+	for i := range res.Reasons {
+		res.Reasons[i].Detail = refang.DefangText(res.Reasons[i].Detail)
+		res.Reasons[i].Entry = refang.Defang(res.Reasons[i].Entry)
+	}
+	return res
+}
@@ -0,0 +1,291 @@
+package output
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/rules"
+	"github.com/samuraidays/urwarden/internal/version"
+)
+
+// SARIF 2.1.0 output for code-scanning dashboards. Every reason of a result
+// becomes one SARIF result whose ruleId is the urwarden rule name. The
+// document is streamed: the tool section is written up front from the
+// built-in rule table, results follow as they arrive.
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolURI      = "https://github.com/samuraidays/urwarden"
+)
+
+// sarifRules describes the built-in rules, in registration order
+var sarifRules = []sarifRule{
+	{
+		ID:               rules.RuleBlocklistHit,
+		Name:             "BlocklistHit",
+		ShortDescription: sarifMessage{Text: "Host is listed on a configured blocklist"},
+		Default:          sarifRuleConfig{Level: "error"},
+	},
+	{
+		ID:               rules.RuleSuspiciousTLD,
+		Name:             "SuspiciousTLD",
+		ShortDescription: sarifMessage{Text: "Host uses a public suffix frequently abused for phishing and malware"},
+		Default:          sarifRuleConfig{Level: "note"},
+	},
+	{
+		ID:               rules.RulePathHasLoginLike,
+		Name:             "PathHasLoginLike",
+		ShortDescription: sarifMessage{Text: "Path or query contains credential-related keywords"},
+		Default:          sarifRuleConfig{Level: "note"},
+	},
+	{
+		ID:               rules.RuleIDNHomograph,
+		Name:             "IDNHomograph",
+		ShortDescription: sarifMessage{Text: "Internationalized host imitates a protected domain or mixes scripts"},
+		Default:          sarifRuleConfig{Level: "warning"},
+	},
+	{
+		ID:               rules.RuleAllowlisted,
+		Name:             "Allowlisted",
+		ShortDescription: sarifMessage{Text: "Host is on the allowlist; other findings are overridden"},
+		Default:          sarifRuleConfig{Level: "none"},
+	},
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Default          sarifRuleConfig `json:"defaultConfiguration"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           *int              `json:"ruleIndex,omitempty"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          sarifProperties   `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifProperties struct {
+	URL      string `json:"url"`
+	Host     string `json:"host"`
+	Score    int    `json:"score"`
+	Label    string `json:"label"`
+	Weight   int    `json:"weight"`
+	List     string `json:"list,omitempty"`
+	Category string `json:"category,omitempty"`
+	Part     string `json:"mimePart,omitempty"`
+	Context  string `json:"context,omitempty"`
+}
+
+// SARIFWriter streams results as a single SARIF 2.1.0 log with one run
+type SARIFWriter struct {
+	w         *bufio.Writer
+	opts      Options
+	started   bool
+	count     int
+	ruleIndex map[string]int
+}
+
+// NewSARIFWriter returns a SARIF writer. Results without a location in an
+// input file (command line arguments, STDIN) are reported without locations.
+func NewSARIFWriter(w io.Writer, opts Options) *SARIFWriter {
+	idx := make(map[string]int, len(sarifRules))
+	for i, r := range sarifRules {
+		idx[r.ID] = i
+	}
+	return &SARIFWriter{w: bufio.NewWriter(w), opts: opts, ruleIndex: idx}
+}
+
+// Write adds one SARIF result per reason of res. Results without reasons
+// have nothing to report and are skipped.
+func (sw *SARIFWriter) Write(res model.Result) error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	// Fingerprints identify the finding, not how it is displayed: take the
+	// URL before --defang rewrites it
+	url := res.InputURL
+	res = sw.opts.Prepare(res)
+	for _, reason := range res.Reasons {
+		data, err := json.Marshal(sw.result(res, reason, fingerprint(url, reason.Rule)))
+		if err != nil {
+			return err
+		}
+		if sw.count > 0 {
+			if err := sw.w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if _, err := sw.w.Write(append([]byte("\n      "), data...)); err != nil {
+			return err
+		}
+		sw.count++
+	}
+	return nil
+}
+
+// Close terminates the SARIF document and flushes it
+func (sw *SARIFWriter) Close() error {
+	if err := sw.start(); err != nil {
+		return err
+	}
+	if _, err := sw.w.WriteString("\n    ]\n  }]\n}\n"); err != nil {
+		return err
+	}
+	return sw.w.Flush()
+}
+
+// start writes everything up to the opening of the results array
+func (sw *SARIFWriter) start() error {
+	if sw.started {
+		return nil
+	}
+	sw.started = true
+
+	tool, err := json.MarshalIndent(sarifTool{Driver: sarifDriver{
+		Name:           "urwarden",
+		Version:        version.Version,
+		InformationURI: toolURI,
+		Rules:          sarifRules,
+	}}, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(sw.w, "{\n  \"$schema\": %q,\n  \"version\": %q,\n  \"runs\": [{\n    \"tool\": %s,\n    \"results\": [",
+		sarifSchema, sarifVersion, tool)
+	return err
+}
+
+// result builds the SARIF result for one reason of res (already prepared)
+func (sw *SARIFWriter) result(res model.Result, reason model.Reason, fp string) sarifResult {
+	out := sarifResult{
+		RuleID:  reason.Rule,
+		Level:   sarifLevel(res.Label, reason.Rule),
+		Message: sarifMessage{Text: sarifText(res, reason)},
+		PartialFingerprints: map[string]string{
+			"urwardenUrlRule/v1": fp,
+		},
+		Properties: sarifProperties{
+			URL:      res.InputURL,
+			Host:     res.Normalized.Host,
+			Score:    res.Score,
+			Label:    res.Label,
+			Weight:   reason.Weight,
+			List:     reason.List,
+			Category: reason.Category,
+		},
+	}
+	if i, ok := sw.ruleIndex[reason.Rule]; ok {
+		out.RuleIndex = &i
+	}
+	if loc := res.Location; loc != nil {
+		out.Properties.Part = loc.Part
+		out.Properties.Context = loc.Context
+		if artifact, ok := artifactLocation(loc.Source); ok {
+			pl := sarifPhysicalLocation{ArtifactLocation: artifact}
+			// Lines of email input count within the decoded MIME part, not the file
+			if loc.Line > 0 && loc.Part == "" {
+				pl.Region = &sarifRegion{StartLine: loc.Line}
+			}
+			out.Locations = []sarifLocation{{PhysicalLocation: pl}}
+		}
+	}
+	return out
+}
+
+// sarifLevel maps a label to a SARIF result level. Allowlist matches are
+// informational only.
+func sarifLevel(label, rule string) string {
+	if rule == rules.RuleAllowlisted {
+		return "none"
+	}
+	switch label {
+	case "malicious":
+		return "error"
+	case "suspicious":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func sarifText(res model.Result, reason model.Reason) string {
+	text := fmt.Sprintf("%s URL %s (score %d): %s", res.Label, res.InputURL, res.Score, reason.Rule)
+	if reason.Detail != "" {
+		text += ": " + reason.Detail
+	}
+	return text
+}
+
+// artifactLocation converts an input source into a SARIF artifact location.
+// Relative paths are resolved against the scanned source root.
+func artifactLocation(source string) (sarifArtifactLocation, bool) {
+	if source == "" || source == "-" || source == "args" {
+		return sarifArtifactLocation{}, false
+	}
+	if filepath.IsAbs(source) {
+		p := filepath.ToSlash(source)
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p // Windows drive letters
+		}
+		return sarifArtifactLocation{URI: "file://" + p}, true
+	}
+	uri := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(source)), "./")
+	return sarifArtifactLocation{URI: uri, URIBaseID: "%SRCROOT%"}, true
+}
+
+// fingerprint identifies a finding across runs independent of its location
+func fingerprint(url, rule string) string {
+	sum := sha256.Sum256([]byte(rule + "\x00" + url))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,159 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/output"
+)
+
+type sarifLog struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []struct {
+					ID string `json:"id"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID    string `json:"ruleId"`
+			RuleIndex *int   `json:"ruleIndex"`
+			Level     string `json:"level"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI       string `json:"uri"`
+						URIBaseID string `json:"uriBaseId"`
+					} `json:"artifactLocation"`
+					Region *struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+			PartialFingerprints map[string]string `json:"partialFingerprints"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func writeSARIF(t *testing.T, results ...model.Result) sarifLog {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := output.New(output.FormatSARIF, &buf, output.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v\n%s", err, buf.String())
+	}
+	return log
+}
+
+func TestSARIFWriter(t *testing.T) {
+	fromFile := sampleResult()
+	fromFile.Location = &model.Location{Source: "deploy/app.yaml", Line: 12}
+
+	custom := sampleResult()
+	custom.Label = "suspicious"
+	custom.Reasons = []model.Reason{{Rule: "shortener", Weight: 30}}
+
+	benign := sampleResult()
+	benign.Label = "benign"
+	benign.Reasons = nil
+
+	log := writeSARIF(t, fromFile, custom, benign)
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "urwarden" || len(run.Tool.Driver.Rules) == 0 || run.Tool.Driver.Rules[0].ID != "blocklist_hit" {
+		t.Errorf("unexpected driver: %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("want one SARIF result per reason (3), got %d", len(run.Results))
+	}
+
+	first := run.Results[0]
+	if first.RuleID != "blocklist_hit" || first.RuleIndex == nil || *first.RuleIndex != 0 || first.Level != "error" {
+		t.Errorf("first result = %+v", first)
+	}
+	if len(first.Locations) != 1 {
+		t.Fatalf("want a location for file input, got %+v", first.Locations)
+	}
+	loc := first.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "deploy/app.yaml" || loc.ArtifactLocation.URIBaseID != "%SRCROOT%" || loc.Region == nil || loc.Region.StartLine != 12 {
+		t.Errorf("location = %+v", loc)
+	}
+	if first.PartialFingerprints["urwardenUrlRule/v1"] == "" {
+		t.Errorf("missing fingerprint")
+	}
+
+	last := run.Results[2]
+	if last.RuleID != "shortener" || last.RuleIndex != nil || last.Level != "warning" || len(last.Locations) != 0 {
+		t.Errorf("custom rule result = %+v", last)
+	}
+}
+
+func TestSARIFWriter_EmptyRun(t *testing.T) {
+	log := writeSARIF(t)
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 0 {
+		t.Fatalf("empty run must be a valid log without results: %+v", log)
+	}
+}
+
+func TestSARIFWriter_DescribesBuiltinRules(t *testing.T) {
+	log := writeSARIF(t)
+	var ids []string
+	for _, r := range log.Runs[0].Tool.Driver.Rules {
+		ids = append(ids, r.ID)
+	}
+	for _, name := range config.BuiltinRules {
+		if !slices.Contains(ids, name) {
+			t.Errorf("built-in rule %q has no SARIF rule entry (have %v)", name, ids)
+		}
+	}
+}
+
+func TestSARIFWriter_FingerprintIgnoresDefang(t *testing.T) {
+	fingerprints := func(defang bool) []string {
+		var buf bytes.Buffer
+		w, err := output.New(output.FormatSARIF, &buf, output.Options{Defang: defang})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(sampleResult()); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var log sarifLog
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, r := range log.Runs[0].Results {
+			out = append(out, r.PartialFingerprints["urwardenUrlRule/v1"])
+		}
+		return out
+	}
+	plain, defanged := fingerprints(false), fingerprints(true)
+	if len(plain) == 0 || !slices.Equal(plain, defanged) {
+		t.Errorf("fingerprints = %v with --defang, %v without", defanged, plain)
+	}
+}
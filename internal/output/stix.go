@@ -0,0 +1,259 @@
+package output
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/refang"
+	"github.com/samuraidays/urwarden/internal/rules"
+)
+
+// STIX 2.1 output for threat-intel platforms. Suspicious and malicious
+// results become indicator objects with STIX patterns; benign results are
+// left out. The bundle is streamed like the SARIF log.
+
+// stixTime is the STIX timestamp layout (UTC, millisecond precision)
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// stixNamespace is the UUIDv5 namespace for deterministic urwarden IDs
+var stixNamespace = [16]byte{
+	0x6f, 0x0a, 0x3c, 0x52, 0x1b, 0x9e, 0x4d, 0x35,
+	0x9a, 0x51, 0x2e, 0x8c, 0x43, 0xd7, 0x0b, 0x19,
+}
+
+// stixIdentity is the producer referenced by every indicator
+var stixIdentity = stixIdentityObject{
+	Type:          "identity",
+	SpecVersion:   "2.1",
+	ID:            "identity--" + uuid5("urwarden"),
+	Created:       "2024-01-01T00:00:00.000Z",
+	Modified:      "2024-01-01T00:00:00.000Z",
+	Name:          "urwarden",
+	IdentityClass: "system",
+}
+
+type stixIdentityObject struct {
+	Type          string `json:"type"`
+	SpecVersion   string `json:"spec_version"`
+	ID            string `json:"id"`
+	Created       string `json:"created"`
+	Modified      string `json:"modified"`
+	Name          string `json:"name"`
+	IdentityClass string `json:"identity_class"`
+}
+
+type stixIndicator struct {
+	Type               string            `json:"type"`
+	SpecVersion        string            `json:"spec_version"`
+	ID                 string            `json:"id"`
+	CreatedByRef       string            `json:"created_by_ref"`
+	Created            string            `json:"created"`
+	Modified           string            `json:"modified"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	IndicatorTypes     []string          `json:"indicator_types"`
+	Pattern            string            `json:"pattern"`
+	PatternType        string            `json:"pattern_type"`
+	ValidFrom          string            `json:"valid_from"`
+	Labels             []string          `json:"labels"`
+	Confidence         int               `json:"confidence"`
+	ExternalReferences []stixExternalRef `json:"external_references"`
+}
+
+type stixExternalRef struct {
+	SourceName  string `json:"source_name"`
+	ExternalID  string `json:"external_id"`
+	Description string `json:"description,omitempty"`
+}
+
+// hostRules are verdicts about the host itself; only they justify a
+// domain-name pattern in addition to the URL pattern
+var hostRules = map[string]bool{
+	rules.RuleBlocklistHit: true,
+	rules.RuleIDNHomograph: true,
+}
+
+// STIXWriter streams suspicious and malicious results as a STIX 2.1 bundle.
+// Patterns are machine-readable and therefore never defanged; with
+// Options.Defang the name, description and references are.
+type STIXWriter struct {
+	w       *bufio.Writer
+	opts    Options
+	started bool
+}
+
+// NewSTIXWriter returns a STIX 2.1 bundle writer
+func NewSTIXWriter(w io.Writer, opts Options) *STIXWriter {
+	return &STIXWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+// Write adds an indicator for suspicious and malicious results
+func (sx *STIXWriter) Write(res model.Result) error {
+	if err := sx.start(); err != nil {
+		return err
+	}
+	if res.Label != "suspicious" && res.Label != "malicious" {
+		return nil
+	}
+	data, err := json.Marshal(stixIndicatorFor(res, sx.opts))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(sx.w, ",\n    %s", data)
+	return err
+}
+
+// Close terminates the bundle and flushes it
+func (sx *STIXWriter) Close() error {
+	if err := sx.start(); err != nil {
+		return err
+	}
+	if _, err := sx.w.WriteString("\n  ]\n}\n"); err != nil {
+		return err
+	}
+	return sx.w.Flush()
+}
+
+// start writes the bundle header and the identity object
+func (sx *STIXWriter) start() error {
+	if sx.started {
+		return nil
+	}
+	sx.started = true
+
+	identity, err := json.Marshal(stixIdentity)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(sx.w, "{\n  \"type\": \"bundle\",\n  \"id\": \"bundle--%s\",\n  \"objects\": [\n    %s",
+		uuid4(), identity)
+	return err
+}
+
+// stixIndicatorFor builds the indicator for res. The pattern uses the result
+// as scored, the human-readable fields the result prepared under opts.
+func stixIndicatorFor(res model.Result, opts Options) stixIndicator {
+	url := canonicalURL(res.Normalized)
+	shown, shownURL := opts.Prepare(res), url
+	if opts.Defang { // only suspicious and malicious results get here
+		shownURL = refang.Defang(url)
+	}
+	ts := res.Timestamp.UTC()
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+
+	pattern := fmt.Sprintf("[url:value = '%s']", stixEscape(url))
+	indicatorType := "anomalous-activity"
+	if res.Label == "malicious" {
+		indicatorType = "malicious-activity"
+	}
+
+	labels := []string{res.Label}
+	refs := make([]stixExternalRef, 0, len(res.Reasons))
+	hostVerdict := false
+	for _, r := range shown.Reasons {
+		hostVerdict = hostVerdict || hostRules[r.Rule]
+		if r.Category != "" && !slices.Contains(labels, r.Category) {
+			labels = append(labels, r.Category)
+		}
+		desc := r.Detail
+		if r.List != "" {
+			desc = fmt.Sprintf("%s (list %s)", desc, r.List)
+		}
+		refs = append(refs, stixExternalRef{
+			SourceName:  "urwarden",
+			ExternalID:  r.Rule,
+			Description: strings.TrimSpace(fmt.Sprintf("weight %d: %s", r.Weight, desc)),
+		})
+	}
+	if hostVerdict && res.Normalized.Host != "" {
+		pattern += " OR " + hostPattern(res.Normalized.Host)
+	}
+
+	return stixIndicator{
+		Type:               "indicator",
+		SpecVersion:        "2.1",
+		ID:                 "indicator--" + uuid4(),
+		CreatedByRef:       stixIdentity.ID,
+		Created:            ts.Format(stixTime),
+		Modified:           ts.Format(stixTime),
+		Name:               fmt.Sprintf("%s URL %s", res.Label, shownURL),
+		Description:        fmt.Sprintf("urwarden scored %s %d (%s)", shownURL, res.Score, res.Label),
+		IndicatorTypes:     []string{indicatorType},
+		Pattern:            pattern,
+		PatternType:        "stix",
+		ValidFrom:          ts.Format(stixTime),
+		Labels:             labels,
+		Confidence:         min(max(res.Score, 0), 100),
+		ExternalReferences: refs,
+	}
+}
+
+// hostPattern matches the host itself: a domain name or an IP address
+func hostPattern(host string) string {
+	switch ip := net.ParseIP(host); {
+	case ip == nil:
+		return fmt.Sprintf("[domain-name:value = '%s']", stixEscape(host))
+	case ip.To4() != nil:
+		return fmt.Sprintf("[ipv4-addr:value = '%s']", ip)
+	default:
+		return fmt.Sprintf("[ipv6-addr:value = '%s']", ip)
+	}
+}
+
+// canonicalURL rebuilds the normalized URL (refanged, lowercase host) with
+// its port, bracketing IPv6 hosts
+func canonicalURL(n model.NormalizedURL) string {
+	host := n.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if n.Port != "" {
+		host += ":" + n.Port
+	}
+	u := n.Scheme + "://" + host + n.Path
+	if n.Query != "" {
+		u += "?" + n.Query
+	}
+	return u
+}
+
+// stixEscape escapes a string literal for a STIX pattern
+func stixEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// uuid4 returns a random RFC 9562 version 4 UUID
+func uuid4() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+// uuid5 returns the name-based version 5 UUID of name in stixNamespace
+func uuid5(name string) string {
+	h := sha1.New()
+	h.Write(stixNamespace[:])
+	h.Write([]byte(name))
+	var b [16]byte
+	copy(b[:], h.Sum(nil))
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
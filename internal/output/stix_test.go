@@ -0,0 +1,159 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/output"
+	"github.com/samuraidays/urwarden/internal/parse"
+)
+
+type stixObject struct {
+	Type               string   `json:"type"`
+	SpecVersion        string   `json:"spec_version"`
+	ID                 string   `json:"id"`
+	CreatedByRef       string   `json:"created_by_ref"`
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	IndicatorTypes     []string `json:"indicator_types"`
+	Pattern            string   `json:"pattern"`
+	PatternType        string   `json:"pattern_type"`
+	ValidFrom          string   `json:"valid_from"`
+	Labels             []string `json:"labels"`
+	Confidence         int      `json:"confidence"`
+	ExternalReferences []struct {
+		SourceName  string `json:"source_name"`
+		ExternalID  string `json:"external_id"`
+		Description string `json:"description"`
+	} `json:"external_references"`
+}
+
+var stixID = regexp.MustCompile(`^[a-z-]+--[0-9a-f]{8}-[0-9a-f]{4}-[45][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestSTIXWriter(t *testing.T) {
+	malicious := sampleResult()
+	malicious.Normalized.Scheme = "https"
+	malicious.Normalized.Path = "/it's"
+	malicious.Reasons[0].Category = "phishing"
+
+	suspicious := sampleResult()
+	suspicious.Label = "suspicious"
+	suspicious.Score = 30
+	suspicious.Reasons = []model.Reason{{Rule: "suspicious_tld", Weight: 20, Detail: "xyz"}}
+
+	benign := sampleResult()
+	benign.Label = "benign"
+
+	var buf bytes.Buffer
+	w, err := output.New(output.FormatSTIX, &buf, output.Options{Defang: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range []model.Result{malicious, suspicious, benign} {
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle struct {
+		Type    string       `json:"type"`
+		ID      string       `json:"id"`
+		Objects []stixObject `json:"objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("invalid bundle JSON: %v\n%s", err, buf.String())
+	}
+	if bundle.Type != "bundle" || !stixID.MatchString(bundle.ID) {
+		t.Fatalf("bundle header = %s %s", bundle.Type, bundle.ID)
+	}
+	if len(bundle.Objects) != 3 {
+		t.Fatalf("want identity + 2 indicators, got %d objects", len(bundle.Objects))
+	}
+
+	identity, mal, sus := bundle.Objects[0], bundle.Objects[1], bundle.Objects[2]
+	if identity.Type != "identity" || mal.CreatedByRef != identity.ID {
+		t.Errorf("indicators must reference the identity: %+v", mal)
+	}
+	for _, o := range bundle.Objects {
+		if o.SpecVersion != "2.1" || !stixID.MatchString(o.ID) {
+			t.Errorf("object %s: spec_version=%s id=%s", o.Type, o.SpecVersion, o.ID)
+		}
+	}
+
+	wantPattern := `[url:value = 'https://bad.example.com/it\'s'] OR [domain-name:value = 'bad.example.com']`
+	if mal.Pattern != wantPattern || mal.PatternType != "stix" {
+		t.Errorf("pattern = %s, want %s (never defanged)", mal.Pattern, wantPattern)
+	}
+	// Human-readable fields follow --defang
+	if mal.Name != `malicious URL hxxps://bad[.]example[.]com/it's` || !strings.Contains(mal.Description, "hxxps://bad[.]example[.]com") {
+		t.Errorf("name = %q, description = %q, want them defanged", mal.Name, mal.Description)
+	}
+	if d := mal.ExternalReferences[0].Description; d != "weight 70: bad[.]example[.]com" {
+		t.Errorf("reference description = %q, want it defanged", d)
+	}
+	if mal.IndicatorTypes[0] != "malicious-activity" || mal.Confidence != 80 || mal.ValidFrom != "2026-01-02T03:04:05.000Z" {
+		t.Errorf("malicious indicator = %+v", mal)
+	}
+	if len(mal.Labels) != 2 || mal.Labels[0] != "malicious" || mal.Labels[1] != "phishing" {
+		t.Errorf("labels = %v", mal.Labels)
+	}
+	if len(mal.ExternalReferences) != 2 || mal.ExternalReferences[0].ExternalID != "blocklist_hit" || mal.ExternalReferences[0].SourceName != "urwarden" {
+		t.Errorf("external_references = %+v", mal.ExternalReferences)
+	}
+
+	// Path and TLD findings do not implicate the whole domain
+	if sus.Pattern != `[url:value = 'https://bad.example.com']` || sus.IndicatorTypes[0] != "anomalous-activity" {
+		t.Errorf("suspicious indicator = %+v", sus)
+	}
+}
+
+func TestSTIXWriter_PortsAndIPs(t *testing.T) {
+	tests := map[string]string{
+		"https://evil.example:8443/x": `[url:value = 'https://evil.example:8443/x']`,
+		"http://[2001:db8::1]:8080/a": `[url:value = 'http://[2001:db8::1]:8080/a']`,
+		"http://[2001:db8::2]/":       `[url:value = 'http://[2001:db8::2]/']`,
+		"http://192.0.2.7/a":          `[url:value = 'http://192.0.2.7/a'] OR [ipv4-addr:value = '192.0.2.7']`,
+		"http://[2001:db8::3]/a":      `[url:value = 'http://[2001:db8::3]/a'] OR [ipv6-addr:value = '2001:db8::3']`,
+	}
+	for in, want := range tests {
+		n, err := parse.NormalizeURL(in)
+		if err != nil {
+			t.Fatalf("NormalizeURL(%q): %v", in, err)
+		}
+		res := sampleResult()
+		res.InputURL, res.Normalized = in, n
+		res.Reasons = []model.Reason{{Rule: "path_has_login_like", Weight: 40, Detail: "login"}}
+		if strings.Contains(want, "-addr:") {
+			// A host verdict adds a pattern for the address itself
+			res.Reasons = []model.Reason{{Rule: "blocklist_hit", Weight: 70, Detail: n.Host}}
+		}
+
+		var buf bytes.Buffer
+		w, err := output.New(output.FormatSTIX, &buf, output.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(res); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var bundle struct {
+			Objects []stixObject `json:"objects"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil || len(bundle.Objects) != 2 {
+			t.Fatalf("%s: bundle = %v, %s", in, err, buf.String())
+		}
+		if got := bundle.Objects[1].Pattern; got != want {
+			t.Errorf("%s: pattern = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// summaryLabels fixes the order labels are reported in
+var summaryLabels = []string{"malicious", "suspicious", "benign"}
+
+// Summary counts results of a run by label and by rule
+type Summary struct {
+	Scored      int
+	Filtered    int // scored but left out by --only-labels or --min-score
+	InputErrors int
+	Labels      map[string]int
+	Rules       map[string]int
+}
+
+// NewSummary returns an empty summary
+func NewSummary() *Summary {
+	return &Summary{Labels: make(map[string]int), Rules: make(map[string]int)}
+}
+
+// Add counts a scored result
+func (s *Summary) Add(res model.Result) {
+	s.Scored++
+	s.Labels[res.Label]++
+	for _, r := range res.Reasons {
+		s.Rules[r.Rule]++
+	}
+}
+
+// AddFiltered counts a scored result that was not written. Call Add for it
+// as well; the label and rule counts cover every scored result.
+func (s *Summary) AddFiltered() {
+	s.Filtered++
+}
+
+// AddError counts an input that could not be scored
+func (s *Summary) AddError() {
+	s.InputErrors++
+}
+
+// Write prints the summary, e.g. to stderr at the end of a run
+func (s *Summary) Write(w io.Writer) error {
+	head := fmt.Sprintf("Summary: %d URLs scored", s.Scored)
+	if s.Filtered > 0 {
+		head += fmt.Sprintf(" (%d filtered out)", s.Filtered)
+	}
+	if _, err := fmt.Fprintf(w, "%s, %d input errors\n", head, s.InputErrors); err != nil {
+		return err
+	}
+
+	labels := slices.Clone(summaryLabels)
+	for _, l := range slices.Sorted(maps.Keys(s.Labels)) {
+		if !slices.Contains(labels, l) {
+			labels = append(labels, l)
+		}
+	}
+	for _, l := range labels {
+		if _, err := fmt.Fprintf(w, "  %-20s %d\n", l, s.Labels[l]); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Rules) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "Rules:"); err != nil {
+		return err
+	}
+	// Most frequent first, then by name
+	rules := slices.SortedFunc(maps.Keys(s.Rules), func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.Rules[b], s.Rules[a]), cmp.Compare(a, b))
+	})
+	for _, r := range rules {
+		if _, err := fmt.Fprintf(w, "  %-20s %d\n", r, s.Rules[r]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
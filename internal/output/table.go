@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// tableFlushRows bounds how many rows are buffered for column alignment, so
+// that long runs keep streaming; columns are realigned per block
+const tableFlushRows = 500
+
+// ANSI colours by label. All codes have the same length so that tabwriter
+// alignment is not affected.
+var labelColors = map[string]string{
+	"malicious":  "\x1b[31m", // red
+	"suspicious": "\x1b[33m", // yellow
+	"benign":     "\x1b[32m", // green
+}
+
+const colorReset = "\x1b[0m"
+
+// TableWriter renders results as aligned columns for terminals
+type TableWriter struct {
+	tw   *tabwriter.Writer
+	opts Options
+	rows int
+}
+
+// NewTableWriter returns a table writer. With opts.Color the label column
+// is coloured.
+func NewTableWriter(w io.Writer, opts Options) *TableWriter {
+	return &TableWriter{tw: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), opts: opts}
+}
+
+// Write adds one row, preceded by the header row at the start of each block
+func (tw *TableWriter) Write(res model.Result) error {
+	if tw.rows%tableFlushRows == 0 {
+		if tw.rows > 0 {
+			if err := tw.tw.Flush(); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(tw.tw, tw.label("LABEL")+"\tSCORE\tURL\tREASONS"); err != nil {
+			return err
+		}
+	}
+	tw.rows++
+
+	res = tw.opts.Prepare(res)
+	reasons := "-"
+	if len(res.Reasons) > 0 {
+		parts := make([]string, len(res.Reasons))
+		for i, r := range res.Reasons {
+			parts[i] = r.Rule + "(" + strconv.Itoa(r.Weight) + ")"
+		}
+		reasons = strings.Join(parts, " ")
+	}
+	_, err := fmt.Fprintf(tw.tw, "%s\t%d\t%s\t%s\n",
+		tw.label(res.Label), res.Score, sanitizeCell(res.InputURL), reasons)
+	return err
+}
+
+// Close flushes buffered rows
+func (tw *TableWriter) Close() error {
+	return tw.tw.Flush()
+}
+
+// label colours a label cell when enabled
+func (tw *TableWriter) label(s string) string {
+	if !tw.opts.Color {
+		return s
+	}
+	color, ok := labelColors[s]
+	if !ok {
+		color = "\x1b[39m" // default foreground, same length as the others
+	}
+	return color + s + colorReset
+}
+
+// sanitizeCell keeps control characters from breaking the layout or
+// injecting terminal escape sequences
+func sanitizeCell(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,85 @@
+package output_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/output"
+)
+
+func TestTableWriter(t *testing.T) {
+	benign := sampleResult()
+	benign.InputURL = "https://example.com/\x1b[2Jx"
+	benign.Label = "benign"
+	benign.Score = 0
+	benign.Reasons = nil
+
+	render := func(opts output.Options) string {
+		var buf bytes.Buffer
+		w, err := output.New(output.FormatTable, &buf, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, res := range []model.Result{sampleResult(), benign} {
+			if err := w.Write(res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	plain := render(output.Options{})
+	lines := strings.Split(strings.TrimRight(plain, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want header + 2 rows, got:\n%s", plain)
+	}
+	if !strings.HasPrefix(lines[0], "LABEL      SCORE  URL") {
+		t.Errorf("header = %q", lines[0])
+	}
+	// Columns are aligned: URLs start at the same offset
+	if strings.Index(lines[1], "https://") != strings.Index(lines[2], "https://") {
+		t.Errorf("columns not aligned:\n%s", plain)
+	}
+	if !strings.Contains(lines[1], "blocklist_hit(70) path_has_login_like(10)") || !strings.HasSuffix(lines[2], " -") {
+		t.Errorf("unexpected reasons:\n%s", plain)
+	}
+	if strings.Contains(plain, "\x1b") {
+		t.Errorf("escape sequences must not pass through without colour: %q", plain)
+	}
+
+	colored := render(output.Options{Color: true})
+	if !strings.Contains(colored, "\x1b[31mmalicious\x1b[0m") || !strings.Contains(colored, "\x1b[32mbenign\x1b[0m") {
+		t.Errorf("labels not coloured: %q", colored)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	s := output.NewSummary()
+	s.Add(sampleResult())
+	suspicious := sampleResult()
+	suspicious.Label = "suspicious"
+	suspicious.Reasons = suspicious.Reasons[1:]
+	s.Add(suspicious)
+	s.AddFiltered()
+	s.AddError()
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "Summary: 2 URLs scored (1 filtered out), 1 input errors\n" +
+		"  malicious            1\n" +
+		"  suspicious           1\n" +
+		"  benign               0\n" +
+		"Rules:\n" +
+		"  path_has_login_like  2\n" +
+		"  blocklist_hit        1\n"
+	if buf.String() != want {
+		t.Errorf("summary =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// Writer renders a stream of results in one output format.
+// Writers are not safe for concurrent use.
+type Writer interface {
+	// Write renders a single result. Formats that describe a whole run
+	// (e.g. documents with a header) may buffer until Close.
+	Write(res model.Result) error
+	// Close completes the output. It does not close the underlying io.Writer.
+	Close() error
+}
+
+// Format names an output format
+type Format string
+
+const (
+	FormatJSON  Format = "json" // JSON Lines, one object per result
+	FormatCSV   Format = "csv"
+	FormatTSV   Format = "tsv"
+	FormatSARIF Format = "sarif" // SARIF 2.1.0 log for code-scanning tools
+	FormatSTIX  Format = "stix"  // STIX 2.1 bundle of indicators
+	FormatTable Format = "table" // aligned columns for terminals
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatJSON, FormatCSV, FormatTSV, FormatSARIF, FormatSTIX, FormatTable}
+
+// ParseFormat validates a format name given on the command line
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if f == "" {
+		return FormatJSON, nil
+	}
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, known := range Formats {
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("unknown output format %q (use %s)", s, strings.Join(names, ", "))
+}
+
+// New returns a writer for format that writes to w and applies opts to
+// every result
+func New(format Format, w io.Writer, opts Options) (Writer, error) {
+	switch format {
+	case FormatJSON, "":
+		return NewJSONWriter(w, opts), nil
+	case FormatCSV:
+		return NewCSVWriter(w, ',', opts), nil
+	case FormatTSV:
+		return NewCSVWriter(w, '\t', opts), nil
+	case FormatSARIF:
+		return NewSARIFWriter(w, opts), nil
+	case FormatSTIX:
+		return NewSTIXWriter(w, opts), nil
+	case FormatTable:
+		return NewTableWriter(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -5,14 +5,16 @@ import (
 	"net/url"
 	"strings"
 
-	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/idn"
 	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/psl"
 	"github.com/samuraidays/urwarden/internal/utils"
 )
 
 // This package handles URL parsing and normalization.
 // It takes a URL string and breaks it down into scheme/host/tld/path/query
-// components, returning a model.NormalizedURL struct.
+// components, plus the public suffix and registrable domain, returning a
+// model.NormalizedURL struct.
 
 // ErrInvalidScheme is returned when an unsupported URL scheme is detected
 var ErrInvalidScheme = errors.New("invalid scheme: only http/https are allowed")
@@ -20,16 +22,22 @@ var ErrInvalidScheme = errors.New("invalid scheme: only http/https are allowed")
 // ErrNoHost is returned when the hostname is empty
 var ErrNoHost = errors.New("invalid url: host is empty")
 
+// ErrInvalidHost is returned when the hostname cannot be converted to ASCII
+var ErrInvalidHost = errors.New("invalid url: host is not a valid domain name")
+
 // NormalizeURL parses a URL string and returns a normalized URL struct.
 // Only http and https schemes are allowed.
 // Returns: NormalizedURL struct on success, error on failure.
 func NormalizeURL(input string) (model.NormalizedURL, error) {
-	logger.Debug("normalizing URL: %s", input)
+	return NormalizeURLWith(psl.Default(), input)
+}
 
+// NormalizeURLWith is NormalizeURL with the public suffix and registrable
+// domain taken from list instead of psl.Default.
+func NormalizeURLWith(list *psl.List, input string) (model.NormalizedURL, error) {
 	// Use Go's standard url.Parse to break down the URL
 	u, err := url.Parse(input)
 	if err != nil {
-		logger.Debug("failed to parse URL: %v", err)
 		return model.NormalizedURL{}, err
 	}
 
@@ -38,7 +46,6 @@ func NormalizeURL(input string) (model.NormalizedURL, error) {
 
 	// Only allow http and https schemes
 	if !utils.IsValidURLScheme(scheme) {
-		logger.Debug("invalid scheme: %s", scheme)
 		return model.NormalizedURL{}, ErrInvalidScheme
 	}
 
@@ -47,28 +54,43 @@ func NormalizeURL(input string) (model.NormalizedURL, error) {
 
 	// Hostname cannot be empty
 	if host == "" {
-		logger.Debug("empty hostname")
 		return model.NormalizedURL{}, ErrNoHost
 	}
 
+	// Convert IDN hosts to their ASCII (punycode) form and keep the Unicode
+	// form alongside for display and homograph checks
+	ascii, err := idn.ToASCII(host)
+	if err != nil {
+		return model.NormalizedURL{}, ErrInvalidHost
+	}
+	host = strings.ToLower(ascii)
+	hostUnicode := idn.ToUnicode(host)
+
 	// Extract TLD (simple rule: everything after the last dot)
 	tld := host
 	if i := strings.LastIndex(host, "."); i >= 0 && i+1 < len(host) {
 		tld = host[i+1:]
 	}
 
+	// Public suffix and registrable domain (eTLD+1) via the Public Suffix List
+	publicSuffix := list.PublicSuffix(host)
+	registrable := list.RegistrableDomain(host)
+
 	// Get path and query components
 	path := u.EscapedPath()
 	query := u.RawQuery
 
 	result := model.NormalizedURL{
-		Scheme: scheme,
-		Host:   host,
-		TLD:    tld,
-		Path:   path,
-		Query:  query,
+		Scheme:            scheme,
+		Host:              host,
+		HostUnicode:       hostUnicode,
+		TLD:               tld,
+		PublicSuffix:      publicSuffix,
+		RegistrableDomain: registrable,
+		Port:              u.Port(),
+		Path:              path,
+		Query:             query,
 	}
 
-	logger.Debug("normalized URL: %+v", result)
 	return result, nil
 }
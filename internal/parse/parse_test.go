@@ -41,3 +41,47 @@ func TestNormalizeURL_NoHost(t *testing.T) {
 		t.Fatalf("expected error for empty host")
 	}
 }
+
+func TestNormalizeURL_PublicSuffix(t *testing.T) {
+	cases := []struct {
+		in          string
+		tld         string
+		suffix      string
+		registrable string
+	}{
+		{"https://login.evil.co.uk/", "uk", "co.uk", "evil.co.uk"},
+		{"https://foo.github.io/", "io", "github.io", "foo.github.io"},
+		{"http://192.0.2.1/admin", "1", "", ""},
+	}
+	for _, c := range cases {
+		n, err := parse.NormalizeURL(c.in)
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", c.in, err)
+		}
+		if n.TLD != c.tld || n.PublicSuffix != c.suffix || n.RegistrableDomain != c.registrable {
+			t.Errorf("%s: got tld=%q suffix=%q registrable=%q", c.in, n.TLD, n.PublicSuffix, n.RegistrableDomain)
+		}
+	}
+}
+
+func TestNormalizeURL_IDN(t *testing.T) {
+	cases := []struct {
+		in      string
+		host    string
+		unicode string
+	}{
+		{"https://аpple.com/", "xn--pple-43d.com", "аpple.com"},
+		{"https://XN--PPLE-43D.com/", "xn--pple-43d.com", "аpple.com"},
+		{"https://BÜCHER.de/", "xn--bcher-kva.de", "bücher.de"},
+		{"https://example.com/", "example.com", "example.com"},
+	}
+	for _, c := range cases {
+		n, err := parse.NormalizeURL(c.in)
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", c.in, err)
+		}
+		if n.Host != c.host || n.HostUnicode != c.unicode {
+			t.Errorf("%s: got host=%q unicode=%q, want %q %q", c.in, n.Host, n.HostUnicode, c.host, c.unicode)
+		}
+	}
+}
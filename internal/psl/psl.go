@@ -0,0 +1,181 @@
+package psl
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/samuraidays/urwarden/internal/idn"
+)
+
+// This package implements Public Suffix List lookups
+// (https://publicsuffix.org/list/) so that registrable domains (eTLD+1) can be
+// told apart: "evil.co.uk" has the public suffix "co.uk", and
+// "foo.github.io" is registered separately from "bar.github.io".
+//
+// A snapshot of the list is embedded at build time. Replace
+// public_suffix_list.dat (see `make psl-update`) to refresh it, or load a
+// newer copy at runtime with LoadFile and SetDefault.
+
+//go:embed public_suffix_list.dat
+var embedded string
+
+// List is a parsed Public Suffix List. It is read-only after parsing and safe
+// for concurrent use.
+type List struct {
+	rules      map[string]struct{} // "co.uk"
+	wildcards  map[string]struct{} // "*.ck" stored as "ck"
+	exceptions map[string]struct{} // "!www.ck" stored as "www.ck"
+}
+
+// Parse reads a list in the publicsuffix.org format: one rule per line,
+// "//" comments, "*." wildcard rules and "!" exception rules.
+// Both the ICANN and PRIVATE sections are used.
+func Parse(r io.Reader) (*List, error) {
+	l := &List{
+		rules:      make(map[string]struct{}),
+		wildcards:  make(map[string]struct{}),
+		exceptions: make(map[string]struct{}),
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// Only the first field is the rule; anything after whitespace is ignored
+		if fields := strings.Fields(line); len(fields) > 0 {
+			line = fields[0]
+		}
+		line = strings.ToLower(strings.Trim(line, "."))
+		l.add(line)
+
+		// Hosts are looked up in ASCII form, so IDN rules are indexed twice
+		if !idn.IsASCII(line) {
+			if ascii, err := idn.ToASCII(line); err == nil {
+				l.add(strings.ToLower(ascii))
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read public suffix list: %w", err)
+	}
+	if l.Size() == 0 {
+		return nil, fmt.Errorf("public suffix list contains no rules")
+	}
+	return l, nil
+}
+
+// add indexes a single normalized rule
+func (l *List) add(rule string) {
+	switch {
+	case strings.HasPrefix(rule, "!"):
+		l.exceptions[rule[1:]] = struct{}{}
+	case strings.HasPrefix(rule, "*."):
+		l.wildcards[rule[2:]] = struct{}{}
+	default:
+		l.rules[rule] = struct{}{}
+	}
+}
+
+// LoadFile parses a public suffix list from a local file
+func LoadFile(path string) (*List, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("open public suffix list: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return Parse(f)
+}
+
+// Size returns the number of rules in the list
+func (l *List) Size() int {
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix returns the public suffix (eTLD) of host, e.g. "co.uk" for
+// "evil.co.uk". Hosts matching no rule fall back to their last label, as the
+// PSL algorithm's implicit "*" rule requires. IP addresses have no public
+// suffix and return "".
+func (l *List) PublicSuffix(host string) string {
+	host = strings.ToLower(strings.Trim(host, "."))
+	if host == "" || net.ParseIP(host) != nil {
+		return ""
+	}
+
+	// Walk candidate suffixes from longest to shortest; the first hit is the
+	// longest matching rule, with exceptions taking priority
+	for i := 0; ; {
+		suffix := host[i:]
+		dot := strings.IndexByte(suffix, '.')
+
+		if _, ok := l.exceptions[suffix]; ok {
+			if dot < 0 {
+				return suffix
+			}
+			return suffix[dot+1:]
+		}
+		if _, ok := l.rules[suffix]; ok {
+			return suffix
+		}
+		if dot >= 0 {
+			if _, ok := l.wildcards[suffix[dot+1:]]; ok {
+				return suffix
+			}
+		} else {
+			// Implicit "*" rule: the TLD itself
+			return suffix
+		}
+		i += dot + 1
+	}
+}
+
+// RegistrableDomain returns the eTLD+1 of host, e.g. "evil.co.uk" for
+// "login.evil.co.uk". It returns "" when host is itself a public suffix or an
+// IP address.
+func (l *List) RegistrableDomain(host string) string {
+	host = strings.ToLower(strings.Trim(host, "."))
+	suffix := l.PublicSuffix(host)
+	if suffix == "" || len(host) <= len(suffix) {
+		return ""
+	}
+	rest := host[:len(host)-len(suffix)-1]
+	if i := strings.LastIndexByte(rest, '.'); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return rest + "." + suffix
+}
+
+var (
+	defaultList atomic.Pointer[List]
+	loadOnce    sync.Once
+)
+
+// Default returns the list used by parse.NormalizeURL: the embedded snapshot
+// unless SetDefault has replaced it.
+func Default() *List {
+	loadOnce.Do(func() {
+		l, err := Parse(strings.NewReader(embedded))
+		if err != nil {
+			// The embedded file is checked by tests; this only guards broken builds
+			panic("psl: embedded public suffix list is invalid: " + err.Error())
+		}
+		defaultList.Store(l)
+	})
+	return defaultList.Load()
+}
+
+// SetDefault replaces the list returned by Default, e.g. with a newer copy
+// loaded by LoadFile
+func SetDefault(l *List) {
+	loadOnce.Do(func() {})
+	defaultList.Store(l)
+}
@@ -0,0 +1,96 @@
+package psl_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/psl"
+)
+
+func TestDefaultList(t *testing.T) {
+	l := psl.Default()
+	tests := []struct {
+		host       string
+		suffix     string
+		registered string
+	}{
+		{"example.com", "com", "example.com"},
+		{"www.example.com", "com", "example.com"},
+		{"evil.co.uk", "co.uk", "evil.co.uk"},
+		{"login.evil.co.uk", "co.uk", "evil.co.uk"},
+		{"co.uk", "co.uk", ""},
+		{"foo.github.io", "github.io", "foo.github.io"},
+		{"a.foo.github.io", "github.io", "foo.github.io"},
+		{"github.io", "github.io", ""},
+		{"Example.COM.", "com", "example.com"},
+		{"foo.unknowntld", "unknowntld", "foo.unknowntld"},
+		{"192.0.2.1", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := l.PublicSuffix(tt.host); got != tt.suffix {
+				t.Errorf("PublicSuffix(%q) = %q, want %q", tt.host, got, tt.suffix)
+			}
+			if got := l.RegistrableDomain(tt.host); got != tt.registered {
+				t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.host, got, tt.registered)
+			}
+		})
+	}
+}
+
+func TestWildcardAndException(t *testing.T) {
+	l, err := psl.Parse(strings.NewReader(`
+// comment
+com
+*.ck
+!www.ck
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		host   string
+		suffix string
+	}{
+		{"foo.bar.ck", "bar.ck"},
+		{"www.ck", "ck"},
+		{"a.www.ck", "ck"},
+		{"ck", "ck"},
+	}
+	for _, tt := range tests {
+		if got := l.PublicSuffix(tt.host); got != tt.suffix {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", tt.host, got, tt.suffix)
+		}
+	}
+	if got := l.RegistrableDomain("a.www.ck"); got != "www.ck" {
+		t.Errorf("RegistrableDomain(a.www.ck) = %q, want www.ck", got)
+	}
+}
+
+func TestLoadFileAndSetDefault(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "psl.dat")
+	if err := os.WriteFile(p, []byte("example\ncorp.example\n"), 0o644); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+	l, err := psl.LoadFile(p)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	prev := psl.Default()
+	psl.SetDefault(l)
+	defer psl.SetDefault(prev)
+
+	if got := psl.Default().RegistrableDomain("a.b.corp.example"); got != "b.corp.example" {
+		t.Errorf("RegistrableDomain() = %q, want b.corp.example", got)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	if _, err := psl.Parse(strings.NewReader("// only comments\n")); err == nil {
+		t.Fatalf("expected error for empty list")
+	}
+}
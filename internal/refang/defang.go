@@ -0,0 +1,67 @@
+package refang
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// http(s) URLs, including already defanged ones
+	urlTextPattern = regexp.MustCompile(`(?i)\bh(?:tt|xx)ps?(?:://|\[:\]//|\[://\])[^\s"'<>]+`)
+	// Domain names: two or more labels ending in an alphabetic or punycode TLD
+	domainTextPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+(?:xn--[a-z0-9-]+|[a-z]{2,63})\b`)
+)
+
+// Defang makes a URL or domain safe to paste where links are auto-linked:
+// http(s) becomes hxxp(s) and the dots of the host become [.]. Path and
+// query are left alone. Already defanged input is normalized first, so
+// Defang is idempotent.
+func Defang(s string) string {
+	if s == "" {
+		return s
+	}
+	if refanged, ok := Refang(s); ok {
+		s = refanged
+	}
+
+	scheme, rest, hasScheme := strings.Cut(s, "://")
+	if !hasScheme {
+		scheme, rest = "", s
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority := strings.ReplaceAll(rest[:end], ".", "[.]")
+
+	if !hasScheme {
+		return authority + rest[end:]
+	}
+	switch strings.ToLower(scheme) {
+	case "http":
+		scheme = "hxxp"
+	case "https":
+		scheme = "hxxps"
+	}
+	return scheme + "://" + authority + rest[end:]
+}
+
+// DefangText defangs every URL and domain name found in free text, such as
+// the detail of a rule reason
+func DefangText(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range urlTextPattern.FindAllStringIndex(s, -1) {
+		b.WriteString(defangDomains(s[last:loc[0]]))
+		b.WriteString(Defang(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(defangDomains(s[last:]))
+	return b.String()
+}
+
+func defangDomains(s string) string {
+	return domainTextPattern.ReplaceAllStringFunc(s, func(d string) string {
+		return strings.ReplaceAll(d, ".", "[.]")
+	})
+}
@@ -0,0 +1,111 @@
+package refang
+
+import (
+	"regexp"
+	"strings"
+)
+
+// This package undoes the "defanging" analysts and threat-intel feeds apply
+// to indicators so that they cannot be clicked, e.g.
+// hxxps://evil[.]com/login or evil(dot)com, and applies it to output.
+
+var (
+	// hxxp, hXXps, h**p at the start of the string
+	schemePattern = regexp.MustCompile(`(?i)^h(?:xx|\*\*)p(s?)`)
+	// [://], (://), [:]//, (:)//, [:/]/ after the scheme
+	separatorPattern = regexp.MustCompile(`^(?:\[://\]|\(://\)|\[:\]//|\(:\)//|\[:/\]/)`)
+	// [.], (.), {.}, [dot], (dot), {dot}, with optional surrounding spaces
+	dotPattern = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*(?:\.|dot)\s*[\]\)\}]\s*`)
+	// [at], (at), {at}, [@]
+	atPattern = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*(?:at|@)\s*[\]\)\}]\s*`)
+	// [:] and [/] anywhere else, e.g. before a port
+	colonPattern = regexp.MustCompile(`[\[\(]:[\]\)]`)
+	slashPattern = regexp.MustCompile(`\[/\]`)
+	// "scheme://" at the start, once the separator has been refanged
+	schemeSepPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+)
+
+// Refang returns s with common defang conventions reverted, and whether
+// anything was changed. Only the scheme, the "://" separator and the
+// authority (userinfo, host, port) are refanged: paths and queries
+// legitimately contain text such as "(at)" or "[.]". Indicators without a
+// scheme that had to be refanged (e.g. evil[.]com) get "http://" so they can
+// be parsed as URLs; strings that were not defanged are returned unchanged.
+func Refang(s string) (string, bool) {
+	out := strings.TrimSpace(s)
+	changed := false
+
+	if loc := schemePattern.FindStringSubmatchIndex(out); loc != nil {
+		out = "http" + out[loc[2]:loc[3]] + out[loc[1]:]
+		changed = true
+	}
+	if scheme, rest, ok := cutScheme(out); ok {
+		if loc := separatorPattern.FindStringIndex(rest); loc != nil {
+			out = scheme + "://" + rest[loc[1]:]
+			changed = true
+		}
+	}
+
+	start := 0
+	if loc := schemeSepPattern.FindStringIndex(out); loc != nil {
+		start = loc[1]
+	}
+	end := authorityEnd(out, start)
+	authority := out[start:end]
+	for _, r := range []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{dotPattern, "."},
+		{atPattern, "@"},
+		{colonPattern, ":"},
+		{slashPattern, "/"},
+	} {
+		if next := r.re.ReplaceAllLiteralString(authority, r.repl); next != authority {
+			authority = next
+			changed = true
+		}
+	}
+	if strings.Contains(authority, `\.`) {
+		authority = strings.ReplaceAll(authority, `\.`, ".")
+		changed = true
+	}
+
+	if !changed {
+		return s, false
+	}
+	out = out[:start] + authority + out[end:]
+	if start == 0 {
+		out = "http://" + out
+	}
+	return out, true
+}
+
+// authorityEnd returns the end of the authority starting at start: the first
+// "/", "?" or "#". A defanged "[/]" ends it too and is included, so that it
+// is refanged with the host.
+func authorityEnd(s string, start int) int {
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '?', '#':
+			return i
+		case '/':
+			if i > start && s[i-1] == '[' && i+1 < len(s) && s[i+1] == ']' {
+				return i + 2
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// cutScheme splits s after a leading http or https scheme name
+func cutScheme(s string) (scheme, rest string, ok bool) {
+	lower := strings.ToLower(s)
+	for _, sch := range []string{"https", "http"} {
+		if strings.HasPrefix(lower, sch) {
+			return s[:len(sch)], s[len(sch):], true
+		}
+	}
+	return "", "", false
+}
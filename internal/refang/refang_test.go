@@ -0,0 +1,81 @@
+package refang_test
+
+import (
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/refang"
+)
+
+func TestRefang(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		changed bool
+	}{
+		{"hxxps://evil[.]com/login", "https://evil.com/login", true},
+		{"hXXp://evil[.]example(.)com", "http://evil.example.com", true},
+		{"h**ps://evil.com", "https://evil.com", true},
+		{"https[:]//evil.com", "https://evil.com", true},
+		{"hxxps[://]evil.com/a", "https://evil.com/a", true},
+		{"http://evil{.}com[:]8080/x", "http://evil.com:8080/x", true},
+		{"evil(dot)com", "http://evil.com", true},
+		{"evil [dot] example [DOT] com", "http://evil.example.com", true},
+		{"https://user[at]evil.com/", "https://user@evil.com/", true},
+		{`evil\.com`, "http://evil.com", true},
+		{"https://example.com/a(b)", "https://example.com/a(b)", false},
+		{"https://example.com/", "https://example.com/", false},
+		{"example.com", "example.com", false},
+		{"evil[.]com[/]login", "http://evil.com/login", true},
+		{"hxxps://evil[.]com/a[.]b?q=(at)x", "https://evil.com/a[.]b?q=(at)x", true},
+		{"evil[.]com/?next=http://a(dot)b", "http://evil.com/?next=http://a(dot)b", true},
+		// Path and query text of URLs that were never defanged stays as is
+		{"https://example.com/search?q=(at)home", "https://example.com/search?q=(at)home", false},
+		{"https://en.wikipedia.org/wiki/Foo_(dot)_bar", "https://en.wikipedia.org/wiki/Foo_(dot)_bar", false},
+		{"https://example.com/?tag=[.]", "https://example.com/?tag=[.]", false},
+		{`https://example.com/a\.b#[:]`, `https://example.com/a\.b#[:]`, false},
+	}
+	for _, tt := range tests {
+		got, changed := refang.Refang(tt.in)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("Refang(%q) = %q, %v; want %q, %v", tt.in, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestDefang(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://evil.example.com/login.php?a=b.c", "hxxps://evil[.]example[.]com/login.php?a=b.c"},
+		{"HTTP://evil.com", "hxxp://evil[.]com"},
+		{"hxxps://evil[.]com/x", "hxxps://evil[.]com/x"},
+		{"evil.example.com", "evil[.]example[.]com"},
+		{"http://198.51.100.7:8080/", "hxxp://198[.]51[.]100[.]7:8080/"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := refang.Defang(tt.in); got != tt.want {
+			t.Errorf("Defang(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDefangText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"bad.example.com", "bad[.]example[.]com"},
+		{"matched subdomain of example.com", "matched subdomain of example[.]com"},
+		{"redirects to https://evil.com/a.b", "redirects to hxxps://evil[.]com/a.b"},
+		{"matched: login", "matched: login"},
+		{"xyz", "xyz"},
+		{"version 1.2.3", "version 1.2.3"},
+	}
+	for _, tt := range tests {
+		if got := refang.DefangText(tt.in); got != tt.want {
+			t.Errorf("DefangText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/idn"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/psl"
+)
+
+const (
+	// RuleIDNHomograph flags IDN hosts that mix scripts or imitate a protected domain
+	RuleIDNHomograph = "idn_homograph"
+
+	// WeightIDNHomograph is enough on its own to label a URL suspicious
+	WeightIDNHomograph = 50
+)
+
+// homographRule inspects the Unicode form of IDN hosts. ASCII-only hosts are
+// never flagged, so ordinary domains pay no cost beyond one scan of the host.
+type homographRule struct {
+	protected map[string]string // skeleton of registrable domain -> protected domain
+	weight    int
+}
+
+// NewHomographRule creates the idn_homograph rule. protected lists brand
+// domains (e.g. "apple.com") whose look-alikes should be flagged.
+func NewHomographRule(protected []string, weight int) Rule {
+	r := &homographRule{protected: make(map[string]string, len(protected)), weight: weight}
+	for _, d := range protected {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		if d == "" {
+			continue
+		}
+		r.protected[idn.Skeleton(idn.ToUnicode(d))] = d
+	}
+	return r
+}
+
+func (r *homographRule) Name() string       { return RuleIDNHomograph }
+func (r *homographRule) DefaultWeight() int { return r.weight }
+
+func (r *homographRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	host := n.HostUnicode
+	if host == "" {
+		host = idn.ToUnicode(n.Host)
+	}
+	if idn.IsASCII(host) {
+		return nil
+	}
+
+	var findings []string
+
+	// Confusable skeleton collision with a protected brand domain
+	registrable := n.RegistrableDomain
+	if registrable == "" {
+		registrable = psl.Default().RegistrableDomain(n.Host)
+	}
+	if registrable != "" {
+		unicodeDomain := idn.ToUnicode(registrable)
+		if brand, ok := r.protected[idn.Skeleton(unicodeDomain)]; ok && brand != registrable {
+			findings = append(findings, "confusable with "+brand)
+		}
+	}
+
+	// Labels mixing scripts that do not belong together
+	for _, label := range strings.Split(host, ".") {
+		if mixed, scripts := idn.MixedScript(label); mixed {
+			findings = append(findings, "mixed scripts "+strings.Join(scripts, "+")+" in "+label)
+		}
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+	detail := strings.Join(findings, "; ")
+	return []model.Reason{{
+		Rule:   RuleIDNHomograph,
+		Weight: r.weight,
+		Detail: detail,
+	}}
+}
@@ -1,11 +1,13 @@
 package rules
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/samuraidays/urwarden/internal/blocklist"
 	"github.com/samuraidays/urwarden/internal/config"
-	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/minisign"
 	"github.com/samuraidays/urwarden/internal/model"
 )
 
@@ -14,45 +16,191 @@ const (
 	RuleBlocklistHit     = "blocklist_hit"       // Blocklist match
 	RuleSuspiciousTLD    = "suspicious_tld"      // Suspicious TLD (domain suffix)
 	RulePathHasLoginLike = "path_has_login_like" // URL path contains login-like keywords
+	RuleAllowlisted      = "allowlisted"         // Allowlist match (overrides the label, see score.Aggregate)
 
 	// Rule weights (score points)
 	WeightBlocklistHit  = 70
 	WeightSuspiciousTLD = 20
 	WeightPathLoginLike = 10
+	WeightAllowlisted   = 0
 )
 
-// Suspicious TLDs that trigger a +20 score when found in URL suffixes
-var suspiciousTLD = map[string]struct{}{
-	"xyz": {}, "top": {}, "click": {}, "help": {}, "shop": {},
-	"live": {}, "cam": {}, "kim": {}, "fit": {}, "country": {},
+// Rule is a single detection check run by the Evaluator.
+//
+// Evaluate returns one reason per finding (usually zero or one) and must be
+// safe for concurrent use. Name must be unique within an Evaluator and is
+// what configuration uses to disable the rule.
+type Rule interface {
+	Name() string
+	DefaultWeight() int
+	Evaluate(n model.NormalizedURL) []model.Reason
+}
+
+// Registry is an ordered set of rules with unique names
+type Registry struct {
+	rules []Rule
+	names map[string]struct{}
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+// Register appends a rule; rules run in registration order
+func (r *Registry) Register(rule Rule) error {
+	name := rule.Name()
+	if name == "" {
+		return fmt.Errorf("rule name must not be empty")
+	}
+	if _, dup := r.names[name]; dup {
+		return fmt.Errorf("rule %q is already registered", name)
+	}
+	r.names[name] = struct{}{}
+	r.rules = append(r.rules, rule)
+	return nil
 }
 
-// Keywords that indicate login/phishing URLs when found in path+query
-var loginLikeTokens = []string{
-	"login", "signin", "verify", "update", "password", "passcode",
-	"secure", "confirm", "invoice", "billing",
+// Has reports whether a rule with the given name is registered
+func (r *Registry) Has(name string) bool {
+	_, ok := r.names[name]
+	return ok
+}
+
+// Rules returns the registered rules in order
+func (r *Registry) Rules() []Rule {
+	return append([]Rule(nil), r.rules...)
 }
 
 // Evaluator holds the rule evaluation state
 type Evaluator struct {
-	blocklist *blocklist.Blocklist
-	config    *config.Config
+	registry *Registry
+	disabled map[string]struct{}
+	config   *config.Config
 }
 
-// NewEvaluator creates a new rule evaluator
+// NewEvaluator creates a new rule evaluator with the built-in rules registered
+// in their default order: blocklist_hit, suspicious_tld, path_has_login_like,
+// idn_homograph, allowlisted.
+// The blocklists in cfg.Blocklists are loaded; when there are none, the single
+// list at blocklistPath is loaded under the name "default".
+// Rules named in cfg.DisabledRules are skipped by EvaluateAll, and weights in
+// cfg.RuleWeights replace the defaults. For blocklist_hit the override only
+// applies to lists without a weight of their own.
+// With cfg.VerifyChecksums or cfg.ListPublicKeys set, a blocklist failing its
+// checksum or signature check is an error; the allowlist is not checked.
+// A configured allowlist that does not exist is an error.
 func NewEvaluator(blocklistPath string, cfg *config.Config) (*Evaluator, error) {
-	bl := blocklist.New(blocklistPath)
-	if err := bl.Load(); err != nil {
+	keys, err := minisign.LoadPublicKeys(cfg.ListPublicKeys)
+	if err != nil {
 		return nil, err
 	}
+	check := blocklist.Integrity{Checksum: cfg.VerifyChecksums, PublicKeys: keys}
+
+	specs := cfg.Blocklists
+	if len(specs) == 0 {
+		specs = []config.BlocklistSpec{{Name: "default", Path: blocklistPath}}
+	}
+	lists := make([]NamedBlocklist, 0, len(specs))
+	for _, spec := range specs {
+		// Resolved once here: a configured blocklist_hit weight of 0 is kept
+		if spec.Weight == 0 {
+			spec.Weight = weightFor(cfg, RuleBlocklistHit, WeightBlocklistHit)
+		}
+		bl := blocklist.NewVerified(spec.Path, check)
+		if err := bl.Load(); err != nil {
+			return nil, fmt.Errorf("blocklist %s: %w", spec.Name, err)
+		}
+		lists = append(lists, NamedBlocklist{Spec: spec, Blocklist: bl})
+	}
+
+	// The allowlist uses the blocklist file format and matching semantics.
+	// Load treats a missing file as empty, which would silently flag hosts
+	// the user meant to exempt, so a configured allowlist must exist.
+	var al *blocklist.Blocklist
+	if cfg.AllowlistPath != "" {
+		if _, err := os.Stat(cfg.AllowlistPath); err != nil {
+			return nil, fmt.Errorf("allowlist: %w", err)
+		}
+		al = blocklist.New(cfg.AllowlistPath)
+		if err := al.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &Evaluator{
+		registry: NewRegistry(),
+		disabled: make(map[string]struct{}, len(cfg.DisabledRules)),
+		config:   cfg,
+	}
+	for _, name := range cfg.DisabledRules {
+		e.disabled[name] = struct{}{}
+	}
 
-	return &Evaluator{
-		blocklist: bl,
-		config:    cfg,
-	}, nil
+	for _, rule := range []Rule{
+		NewBlocklistRule(lists...),
+		NewSuspiciousTLDRule(cfg.SuspiciousTLDs, weightFor(cfg, RuleSuspiciousTLD, WeightSuspiciousTLD)),
+		NewLoginLikePathRule(cfg.LoginKeywords, weightFor(cfg, RulePathHasLoginLike, WeightPathLoginLike)),
+		NewHomographRule(cfg.ProtectedDomains, weightFor(cfg, RuleIDNHomograph, WeightIDNHomograph)),
+		NewAllowlistRule(al),
+	} {
+		if err := e.registry.Register(rule); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// weightFor returns the configured weight override for a rule, or def
+func weightFor(cfg *config.Config, name string, def int) int {
+	if w, ok := cfg.RuleWeights[name]; ok {
+		return w
+	}
+	return def
+}
+
+// Register adds a rule after the ones already registered. A weight
+// configured for the rule's name in cfg.RuleWeights replaces the weight of
+// every reason it returns.
+// It must be called before the evaluator is shared between goroutines.
+func (e *Evaluator) Register(rule Rule) error {
+	if w, ok := e.config.RuleWeights[rule.Name()]; ok {
+		rule = &weightedRule{Rule: rule, weight: w}
+	}
+	return e.registry.Register(rule)
+}
+
+// weightedRule overrides the weight of another rule's reasons
+type weightedRule struct {
+	Rule
+	weight int
 }
 
-// EvaluateAll evaluates all rules against the normalized URL
+func (r *weightedRule) DefaultWeight() int { return r.weight }
+
+func (r *weightedRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	reasons := r.Rule.Evaluate(n)
+	for i := range reasons {
+		reasons[i].Weight = r.weight
+	}
+	return reasons
+}
+
+// Rules returns the registered rules in evaluation order, including disabled ones
+func (e *Evaluator) Rules() []Rule {
+	return e.registry.Rules()
+}
+
+// Enabled reports whether the named rule is registered and not disabled
+func (e *Evaluator) Enabled(name string) bool {
+	if !e.registry.Has(name) {
+		return false
+	}
+	_, off := e.disabled[name]
+	return !off
+}
+
+// EvaluateAll evaluates all enabled rules against the normalized URL
 //
 // Args:
 //
@@ -64,45 +212,177 @@ func NewEvaluator(blocklistPath string, cfg *config.Config) (*Evaluator, error)
 func (e *Evaluator) EvaluateAll(n model.NormalizedURL) []model.Reason {
 	reasons := make([]model.Reason, 0, 3)
 
-	// Rule 1: blocklist_hit
-	if hit, domain := e.blocklist.Contains(n.Host); hit {
+	for _, rule := range e.registry.rules {
+		if _, off := e.disabled[rule.Name()]; off {
+			continue
+		}
+		reasons = append(reasons, rule.Evaluate(n)...)
+	}
+
+	return reasons
+}
+
+// NamedBlocklist is a loaded blocklist with its name, category and weight
+type NamedBlocklist struct {
+	Spec      config.BlocklistSpec
+	Blocklist *blocklist.Blocklist
+}
+
+// blocklistRule flags hosts listed in any of its blocklists (exact or parent
+// domain). Each list that matches contributes its own reason and weight.
+type blocklistRule struct {
+	lists []NamedBlocklist
+}
+
+// NewBlocklistRule creates the blocklist_hit rule for loaded blocklists.
+// Each hit scores Spec.Weight as is; NewEvaluator resolves unset weights
+// before calling it.
+func NewBlocklistRule(lists ...NamedBlocklist) Rule {
+	return &blocklistRule{lists: lists}
+}
+
+func (r *blocklistRule) Name() string       { return RuleBlocklistHit }
+func (r *blocklistRule) DefaultWeight() int { return WeightBlocklistHit }
+
+func (r *blocklistRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	var reasons []model.Reason
+	for _, l := range r.lists {
+		hit, domain := l.Blocklist.ContainsWithin(n.Host, n.RegistrableDomain)
+		if !hit {
+			continue
+		}
 		detail := domain
 		if n.Host != domain && strings.HasSuffix(n.Host, "."+domain) {
 			detail = "matched subdomain of " + domain
 		}
 		reasons = append(reasons, model.Reason{
-			Rule:   RuleBlocklistHit,
-			Weight: WeightBlocklistHit,
-			Detail: detail,
+			Rule:     RuleBlocklistHit,
+			Weight:   l.Spec.Weight,
+			Detail:   detail,
+			List:     l.Spec.Name,
+			Category: l.Spec.Category,
+			Entry:    domain,
 		})
-		logger.Debug("blocklist hit: %s -> %s", n.Host, domain)
 	}
+	return reasons
+}
 
-	// Rule 2: suspicious_tld
-	if _, ok := suspiciousTLD[strings.ToLower(n.TLD)]; ok {
-		reasons = append(reasons, model.Reason{
-			Rule:   RuleSuspiciousTLD,
-			Weight: WeightSuspiciousTLD,
-			Detail: n.TLD,
-		})
-		logger.Debug("suspicious TLD: %s", n.TLD)
+// allowlistRule marks hosts that must never be flagged. It contributes no
+// points; score.Aggregate applies the configured allowlist policy instead.
+type allowlistRule struct {
+	allowlist *blocklist.Blocklist
+}
+
+// NewAllowlistRule creates the allowlisted rule. A nil allowlist never matches.
+func NewAllowlistRule(al *blocklist.Blocklist) Rule {
+	return &allowlistRule{allowlist: al}
+}
+
+func (r *allowlistRule) Name() string       { return RuleAllowlisted }
+func (r *allowlistRule) DefaultWeight() int { return WeightAllowlisted }
+
+func (r *allowlistRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	if r.allowlist == nil {
+		return nil
+	}
+	hit, domain := r.allowlist.ContainsWithin(n.Host, n.RegistrableDomain)
+	if !hit {
+		return nil
 	}
+	return []model.Reason{{
+		Rule:   RuleAllowlisted,
+		Weight: WeightAllowlisted,
+		Detail: domain,
+	}}
+}
 
-	// Rule 3: path_has_login_like
-	if matched := pathHasLoginLike(n.Path, n.Query); matched != "" {
-		reasons = append(reasons, model.Reason{
-			Rule:   RulePathHasLoginLike,
-			Weight: WeightPathLoginLike,
-			Detail: "matched: " + matched,
-		})
-		logger.Debug("login-like path: %s", matched)
+// suspiciousTLDRule flags TLDs commonly used for throwaway phishing domains
+// (config.Default lists them). Multi-label suffixes (e.g. "co.xyz") are
+// matched against the public suffix.
+type suspiciousTLDRule struct {
+	tlds   map[string]struct{}
+	weight int
+}
+
+// NewSuspiciousTLDRule creates the suspicious_tld rule
+func NewSuspiciousTLDRule(tlds []string, weight int) Rule {
+	r := &suspiciousTLDRule{tlds: make(map[string]struct{}, len(tlds)), weight: weight}
+	for _, tld := range tlds {
+		r.tlds[strings.ToLower(strings.Trim(strings.TrimSpace(tld), "."))] = struct{}{}
 	}
+	return r
+}
 
-	return reasons
+func (r *suspiciousTLDRule) Name() string       { return RuleSuspiciousTLD }
+func (r *suspiciousTLDRule) DefaultWeight() int { return r.weight }
+
+func (r *suspiciousTLDRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	matched := suffixInSet(n, r.tlds)
+	if matched == "" {
+		return nil
+	}
+	return []model.Reason{{
+		Rule:   RuleSuspiciousTLD,
+		Weight: r.weight,
+		Detail: matched,
+	}}
+}
+
+// suffixInSet checks the public suffix (falling back to the TLD) and each of
+// its parent suffixes against set, longest first: "com.cn" then "cn".
+func suffixInSet(n model.NormalizedURL, set map[string]struct{}) string {
+	suffix := strings.ToLower(n.PublicSuffix)
+	if suffix == "" {
+		suffix = strings.ToLower(n.TLD)
+	}
+	for suffix != "" {
+		if _, ok := set[suffix]; ok {
+			return suffix
+		}
+		i := strings.IndexByte(suffix, '.')
+		if i < 0 {
+			break
+		}
+		suffix = suffix[i+1:]
+	}
+	return ""
+}
+
+// loginLikePathRule flags credential-harvesting keywords in path and query
+// (config.Default lists them)
+type loginLikePathRule struct {
+	tokens []string
+	weight int
+}
+
+// NewLoginLikePathRule creates the path_has_login_like rule
+func NewLoginLikePathRule(keywords []string, weight int) Rule {
+	r := &loginLikePathRule{tokens: make([]string, 0, len(keywords)), weight: weight}
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			r.tokens = append(r.tokens, k)
+		}
+	}
+	return r
+}
+
+func (r *loginLikePathRule) Name() string       { return RulePathHasLoginLike }
+func (r *loginLikePathRule) DefaultWeight() int { return r.weight }
+
+func (r *loginLikePathRule) Evaluate(n model.NormalizedURL) []model.Reason {
+	matched := pathHasLoginLike(n.Path, n.Query, r.tokens)
+	if matched == "" {
+		return nil
+	}
+	return []model.Reason{{
+		Rule:   RulePathHasLoginLike,
+		Weight: r.weight,
+		Detail: "matched: " + matched,
+	}}
 }
 
 // pathHasLoginLike checks if path and query contain login-like keywords
-func pathHasLoginLike(path, query string) string {
+func pathHasLoginLike(path, query string, tokens []string) string {
 	// Combine path and query in lowercase
 	s := strings.ToLower(path)
 	if query != "" {
@@ -110,7 +390,7 @@ func pathHasLoginLike(path, query string) string {
 	}
 
 	// Check for any login-like tokens
-	for _, token := range loginLikeTokens {
+	for _, token := range tokens {
 		if strings.Contains(s, token) {
 			return token
 		}
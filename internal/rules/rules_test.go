@@ -1,6 +1,7 @@
 package rules_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/samuraidays/urwarden/internal/config"
 	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/parse"
 	"github.com/samuraidays/urwarden/internal/rules"
 )
 
@@ -170,3 +172,233 @@ bad.example.com
 		t.Fatalf("expected blocklist_hit (exact)")
 	}
 }
+
+type staticRule struct {
+	name string
+}
+
+func (r staticRule) Name() string       { return r.name }
+func (r staticRule) DefaultWeight() int { return 5 }
+func (r staticRule) Evaluate(model.NormalizedURL) []model.Reason {
+	return []model.Reason{{Rule: r.name, Weight: 5, Detail: "always"}}
+}
+
+func TestRegisterCustomRule(t *testing.T) {
+	cfg := config.Default()
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+	if err := evaluator.Register(staticRule{name: "custom"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := evaluator.Register(staticRule{name: rules.RuleSuspiciousTLD}); err == nil {
+		t.Fatalf("expected error for duplicate rule name")
+	}
+
+	rs := evaluator.EvaluateAll(model.NormalizedURL{Host: "foo.shop", TLD: "shop"})
+	if len(rs) != 2 || rs[0].Rule != rules.RuleSuspiciousTLD || rs[1].Rule != "custom" {
+		t.Fatalf("rules must run in registration order: %+v", rs)
+	}
+
+	var names []string
+	for _, r := range evaluator.Rules() {
+		names = append(names, r.Name())
+	}
+	want := []string{rules.RuleBlocklistHit, rules.RuleSuspiciousTLD, rules.RulePathHasLoginLike, rules.RuleIDNHomograph, rules.RuleAllowlisted, "custom"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("Rules() = %v, want %v", names, want)
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.DisabledRules = []string{rules.RuleSuspiciousTLD}
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+	if evaluator.Enabled(rules.RuleSuspiciousTLD) {
+		t.Fatalf("suspicious_tld should be disabled")
+	}
+	if !evaluator.Enabled(rules.RulePathHasLoginLike) {
+		t.Fatalf("path_has_login_like should be enabled")
+	}
+
+	rs := evaluator.EvaluateAll(model.NormalizedURL{Host: "foo.shop", TLD: "shop", Path: "/login"})
+	for _, r := range rs {
+		if r.Rule == rules.RuleSuspiciousTLD {
+			t.Fatalf("disabled rule must not fire: %+v", rs)
+		}
+	}
+	if len(rs) != 1 {
+		t.Fatalf("expected only path_has_login_like, got %+v", rs)
+	}
+}
+
+func TestSuspiciousTLD_UsesPublicSuffix(t *testing.T) {
+	cfg := config.Default()
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+	n := model.NormalizedURL{Host: "evil.co.xyz", TLD: "xyz", PublicSuffix: "xyz", RegistrableDomain: "co.xyz"}
+	rs := evaluator.EvaluateAll(n)
+	if len(rs) != 1 || rs[0].Rule != rules.RuleSuspiciousTLD || rs[0].Detail != "xyz" {
+		t.Fatalf("expected suspicious_tld for .xyz, got %+v", rs)
+	}
+}
+
+func TestBlocklist_PublicSuffixEntryDoesNotMatchRegistrants(t *testing.T) {
+	cfg := config.Default()
+	bl := tempBlocklist(t, `
+github.io
+`)
+	evaluator, err := rules.NewEvaluator(bl, cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+	n := model.NormalizedURL{Host: "foo.github.io", TLD: "io", PublicSuffix: "github.io", RegistrableDomain: "foo.github.io"}
+	for _, r := range evaluator.EvaluateAll(n) {
+		if r.Rule == rules.RuleBlocklistHit {
+			t.Fatalf("github.io entry must not match foo.github.io: %+v", r)
+		}
+	}
+}
+
+func TestIDNHomograph(t *testing.T) {
+	cfg := config.Default()
+	cfg.ProtectedDomains = []string{"apple.com"}
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		host   string
+		want   bool
+		detail string
+	}{
+		{"cyrillic a", "аpple.com", true, "confusable with apple.com"},
+		{"punycode form", "xn--pple-43d.com", true, "confusable with apple.com"},
+		{"whole-script cyrillic", "аррӏе.com", true, "confusable with apple.com"},
+		{"mixed scripts only", "gоogle-login.com", true, "mixed scripts Cyrillic+Latin"},
+		{"plain ascii", "apple.com", false, ""},
+		{"legit japanese", "日本ドメイン.jp", false, ""},
+		{"legit german", "bücher.de", false, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			n, err := parse.NormalizeURL("https://" + c.host + "/")
+			if err != nil {
+				t.Fatalf("NormalizeURL() error = %v", err)
+			}
+			var got *model.Reason
+			for _, r := range evaluator.EvaluateAll(n) {
+				if r.Rule == rules.RuleIDNHomograph {
+					got = &r
+				}
+			}
+			if (got != nil) != c.want {
+				t.Fatalf("idn_homograph fired = %v, want %v (host %s)", got != nil, c.want, n.Host)
+			}
+			if got != nil && !strings.Contains(got.Detail, c.detail) {
+				t.Fatalf("detail %q must contain %q", got.Detail, c.detail)
+			}
+		})
+	}
+}
+
+func TestAllowlisted(t *testing.T) {
+	cfg := config.Default()
+	cfg.AllowlistPath = tempBlocklist(t, `
+ourcorp.com
+`)
+	evaluator, err := rules.NewEvaluator(tempBlocklist(t, "ourcorp.com\n"), cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+
+	n := model.NormalizedURL{Host: "login.ourcorp.com", TLD: "com", PublicSuffix: "com", RegistrableDomain: "ourcorp.com", Path: "/login"}
+	rs := evaluator.EvaluateAll(n)
+	found := false
+	for _, r := range rs {
+		if r.Rule == rules.RuleAllowlisted {
+			if r.Detail != "ourcorp.com" || r.Weight != 0 {
+				t.Fatalf("unexpected allowlisted reason: %+v", r)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected allowlisted reason, got %+v", rs)
+	}
+
+	n = model.NormalizedURL{Host: "ourcorp.com.evil.test", TLD: "test", PublicSuffix: "test", RegistrableDomain: "evil.test"}
+	for _, r := range evaluator.EvaluateAll(n) {
+		if r.Rule == rules.RuleAllowlisted {
+			t.Fatalf("allowlist must not match lookalike host: %+v", r)
+		}
+	}
+}
+
+func TestAllowlistMissing(t *testing.T) {
+	cfg := config.Default()
+	cfg.AllowlistPath = filepath.Join(t.TempDir(), "missing.txt")
+	_, err := rules.NewEvaluator(tempBlocklist(t, "bad.example.com\n"), cfg)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("NewEvaluator() error = %v, want a missing allowlist error", err)
+	}
+}
+
+func TestMultipleNamedBlocklists(t *testing.T) {
+	cfg := config.Default()
+	cfg.Blocklists = []config.BlocklistSpec{
+		{Name: "phishing", Path: tempBlocklist(t, "bad.example.com\n"), Category: "phishing", Weight: 80},
+		{Name: "ads", Path: tempBlocklist(t, "example.com\n"), Category: "ads", Weight: 5},
+		{Name: "malware", Path: tempBlocklist(t, "other.test\n"), Category: "malware"},
+	}
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+
+	n := model.NormalizedURL{Host: "x.bad.example.com", TLD: "com", PublicSuffix: "com", RegistrableDomain: "example.com"}
+	rs := evaluator.EvaluateAll(n)
+	if len(rs) != 2 {
+		t.Fatalf("expected hits from two lists, got %+v", rs)
+	}
+	want := []model.Reason{
+		{Rule: rules.RuleBlocklistHit, Weight: 80, Detail: "matched subdomain of bad.example.com", List: "phishing", Category: "phishing", Entry: "bad.example.com"},
+		{Rule: rules.RuleBlocklistHit, Weight: 5, Detail: "matched subdomain of example.com", List: "ads", Category: "ads", Entry: "example.com"},
+	}
+	for i := range want {
+		if rs[i] != want[i] {
+			t.Errorf("reason %d = %+v, want %+v", i, rs[i], want[i])
+		}
+	}
+
+	// A list without an explicit weight uses the default
+	rs = evaluator.EvaluateAll(model.NormalizedURL{Host: "other.test", TLD: "test"})
+	if len(rs) != 1 || rs[0].Weight != rules.WeightBlocklistHit || rs[0].List != "malware" {
+		t.Fatalf("unexpected reasons: %+v", rs)
+	}
+}
+
+func TestBlocklistHitWeightZero(t *testing.T) {
+	cfg := config.Default()
+	cfg.RuleWeights = map[string]int{rules.RuleBlocklistHit: 0}
+	cfg.Blocklists = []config.BlocklistSpec{
+		{Name: "ads", Path: tempBlocklist(t, "ads.test\n")},
+		{Name: "phishing", Path: tempBlocklist(t, "ads.test\n"), Weight: 80},
+	}
+	evaluator, err := rules.NewEvaluator("", cfg)
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+	rs := evaluator.EvaluateAll(model.NormalizedURL{Host: "ads.test", TLD: "test"})
+	if len(rs) != 2 || rs[0].Weight != 0 || rs[1].Weight != 80 {
+		t.Fatalf("reasons = %+v, want weight 0 for ads and 80 for phishing", rs)
+	}
+}
@@ -0,0 +1,28 @@
+package scan
+
+import (
+	"slices"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// Filter selects the results worth reporting, applied after scoring.
+// The zero value keeps every result.
+type Filter struct {
+	Labels   []string // keep only these labels; empty keeps all
+	MinScore int      // keep only results scoring at least this
+}
+
+// FilterFromConfig returns the filter configured by only_labels and min_score
+func FilterFromConfig(cfg *config.Config) Filter {
+	return Filter{Labels: cfg.OnlyLabels, MinScore: cfg.MinScore}
+}
+
+// Keep reports whether res passes the filter
+func (f Filter) Keep(res model.Result) bool {
+	if res.Score < f.MinScore {
+		return false
+	}
+	return len(f.Labels) == 0 || slices.Contains(f.Labels, res.Label)
+}
@@ -0,0 +1,123 @@
+package scan
+
+import (
+	"context"
+	"time"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/parse"
+	"github.com/samuraidays/urwarden/internal/psl"
+	"github.com/samuraidays/urwarden/internal/refang"
+	"github.com/samuraidays/urwarden/internal/rules"
+	"github.com/samuraidays/urwarden/internal/score"
+)
+
+// This package wires the scoring pipeline together:
+// refang.Refang → parse.NormalizeURLWith → rules.Evaluator → score.Aggregate
+// → model.Result.
+// The CLI and the public urwarden package both go through it so they
+// always produce identical results.
+
+// Scanner scores single URLs. It is safe for concurrent use.
+type Scanner struct {
+	evaluator *rules.Evaluator
+	config    *config.Config
+	filter    Filter
+	suffixes  *psl.List
+}
+
+// New loads the blocklist configured in cfg and returns a ready Scanner.
+// When cfg.PublicSuffixListPath is set, this Scanner uses that list instead
+// of the embedded Public Suffix List.
+func New(cfg *config.Config) (*Scanner, error) {
+	suffixes := psl.Default()
+	if cfg.PublicSuffixListPath != "" {
+		list, err := psl.LoadFile(cfg.PublicSuffixListPath)
+		if err != nil {
+			return nil, err
+		}
+		suffixes = list
+		logger.Debug("loaded public suffix list", "path", cfg.PublicSuffixListPath, "rules", list.Size())
+	}
+
+	evaluator, err := rules.NewEvaluator(cfg.BlocklistPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{
+		evaluator: evaluator,
+		config:    cfg,
+		filter:    FilterFromConfig(cfg),
+		suffixes:  suffixes,
+	}, nil
+}
+
+// Evaluator returns the rule evaluator, e.g. to register additional rules.
+// Register rules before the scanner is shared between goroutines.
+func (s *Scanner) Evaluator() *rules.Evaluator {
+	return s.evaluator
+}
+
+// Filter returns the configured result filter. Scan never filters; callers
+// decide what to do with results that do not pass.
+func (s *Scanner) Filter() Filter {
+	return s.filter
+}
+
+// Scan normalizes, evaluates and scores a single URL.
+// Normalization errors from the parse package are returned unwrapped.
+// Debug entries carry the correlation ID of ctx (see logger.WithID); a new
+// one is assigned when ctx has none.
+func (s *Scanner) Scan(ctx context.Context, rawURL string) (model.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Result{}, err
+	}
+	debug := logger.Enabled(logger.LevelDebug)
+	if debug {
+		ctx = logger.WithID(ctx)
+	}
+	start := time.Now()
+
+	// Undo defanging (hxxp, [.] ...); InputURL keeps the original string
+	target, refanged := refang.Refang(rawURL)
+
+	// Parse and normalize URL
+	norm, err := parse.NormalizeURLWith(s.suffixes, target)
+	if err != nil {
+		logger.DebugContext(ctx, "parse failed", "url", rawURL, "error", err)
+		return model.Result{}, err
+	}
+	if debug {
+		logger.DebugContext(ctx, "parsed", "url", rawURL, "refanged", refanged,
+			"host", norm.Host, "registrable_domain", norm.RegistrableDomain)
+	}
+
+	// Evaluate rules
+	reasons := s.evaluator.EvaluateAll(norm)
+	if debug {
+		for _, r := range reasons {
+			args := []any{"url", rawURL, "rule", r.Rule, "weight", r.Weight, "detail", r.Detail}
+			if r.List != "" {
+				args = append(args, "list", r.List)
+			}
+			logger.DebugContext(ctx, "rule matched", args...)
+		}
+	}
+
+	// Calculate score and label
+	total, label := score.Aggregate(reasons, s.config)
+	logger.DebugContext(ctx, "scored", "url", rawURL, "score", total, "label", label,
+		"duration", time.Since(start))
+
+	return model.Result{
+		InputURL:   rawURL,
+		Normalized: norm,
+		Score:      total,
+		Label:      label,
+		Reasons:    reasons,
+		Refanged:   refanged,
+		Timestamp:  time.Now().UTC(),
+	}, nil
+}
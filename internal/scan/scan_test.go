@@ -0,0 +1,239 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/input"
+	"github.com/samuraidays/urwarden/internal/parse"
+	"github.com/samuraidays/urwarden/internal/scan"
+)
+
+func newScanner(t *testing.T) *scan.Scanner {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(p, []byte("bad.example.com\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	cfg := config.Default()
+	cfg.BlocklistPath = p
+	s, err := scan.New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+func TestScan(t *testing.T) {
+	s := newScanner(t)
+	res, err := s.Scan(context.Background(), "https://bad.example.com/login")
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if res.Score != 80 || res.Label != "malicious" {
+		t.Fatalf("got score=%d label=%s, want 80 malicious", res.Score, res.Label)
+	}
+	if res.InputURL != "https://bad.example.com/login" || res.Normalized.Host != "bad.example.com" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.Timestamp.IsZero() {
+		t.Fatalf("timestamp must be set")
+	}
+}
+
+func TestScan_Refanged(t *testing.T) {
+	s := newScanner(t)
+	res, err := s.Scan(context.Background(), "hxxps://bad[.]example[.]com/login")
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !res.Refanged || res.InputURL != "hxxps://bad[.]example[.]com/login" {
+		t.Fatalf("got refanged=%v input_url=%s", res.Refanged, res.InputURL)
+	}
+	if res.Normalized.Host != "bad.example.com" || res.Label != "malicious" {
+		t.Fatalf("got host=%s label=%s", res.Normalized.Host, res.Label)
+	}
+
+	res, err = s.Scan(context.Background(), "https://example.com")
+	if err != nil || res.Refanged {
+		t.Fatalf("plain URL: refanged=%v err=%v", res.Refanged, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := newScanner(t)
+	if f := s.Filter(); f.Labels != nil || f.MinScore != 0 {
+		t.Fatalf("default filter = %+v, want zero", f)
+	}
+	malicious, _ := s.Scan(context.Background(), "https://bad.example.com/login")
+	benign, _ := s.Scan(context.Background(), "https://example.com/")
+
+	tests := []struct {
+		name   string
+		filter scan.Filter
+		want   [2]bool // malicious, benign
+	}{
+		{"zero", scan.Filter{}, [2]bool{true, true}},
+		{"labels", scan.Filter{Labels: []string{"malicious", "suspicious"}}, [2]bool{true, false}},
+		{"min score", scan.Filter{MinScore: 80}, [2]bool{true, false}},
+		{"min score above", scan.Filter{MinScore: 81}, [2]bool{false, false}},
+		{"benign only", scan.Filter{Labels: []string{"benign"}}, [2]bool{false, true}},
+	}
+	for _, tt := range tests {
+		got := [2]bool{tt.filter.Keep(malicious), tt.filter.Keep(benign)}
+		if got != tt.want {
+			t.Errorf("%s: Keep = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScan_InvalidURL(t *testing.T) {
+	s := newScanner(t)
+	_, err := s.Scan(context.Background(), "ftp://bad.example.com")
+	if !errors.Is(err, parse.ErrInvalidScheme) {
+		t.Fatalf("want ErrInvalidScheme, got %v", err)
+	}
+}
+
+func TestScan_CanceledContext(t *testing.T) {
+	s := newScanner(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Scan(ctx, "https://example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestNew_PublicSuffixListPerScanner(t *testing.T) {
+	dir := t.TempDir()
+	pslPath := filepath.Join(dir, "psl.dat")
+	if err := os.WriteFile(pslPath, []byte("com\nexample.com\n"), 0o644); err != nil {
+		t.Fatalf("write psl: %v", err)
+	}
+	cfg := config.Default()
+	cfg.PublicSuffixListPath = pslPath
+	custom, err := scan.New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	embedded := newScanner(t)
+
+	const u = "https://a.example.com/"
+	res, err := custom.Scan(context.Background(), u)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if res.Normalized.PublicSuffix != "example.com" || res.Normalized.RegistrableDomain != "a.example.com" {
+		t.Errorf("custom list: got suffix=%q registrable=%q", res.Normalized.PublicSuffix, res.Normalized.RegistrableDomain)
+	}
+	// The other Scanner keeps the embedded list
+	res, err = embedded.Scan(context.Background(), u)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if res.Normalized.PublicSuffix != "com" || res.Normalized.RegistrableDomain != "example.com" {
+		t.Errorf("embedded list: got suffix=%q registrable=%q", res.Normalized.PublicSuffix, res.Normalized.RegistrableDomain)
+	}
+}
+
+func sliceSource(urls []string) scan.Source {
+	return func(yield func(input.Item) error) error {
+		for i, u := range urls {
+			if err := yield(input.Item{URL: u, Source: "test", Line: i + 1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestScanStream_Ordered(t *testing.T) {
+	s := newScanner(t)
+	var urls []string
+	for i := range 500 {
+		urls = append(urls, fmt.Sprintf("https://host%d.example.com/", i))
+	}
+	urls[7] = "ftp://invalid.example.com"
+
+	var got []scan.Outcome
+	err := s.ScanStream(context.Background(), sliceSource(urls), scan.StreamOptions{Workers: 8, Ordered: true}, func(o scan.Outcome) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanStream() error = %v", err)
+	}
+	if len(got) != len(urls) {
+		t.Fatalf("got %d outcomes, want %d", len(got), len(urls))
+	}
+	for i, o := range got {
+		if o.Item.Line != i+1 || o.Item.URL != urls[i] {
+			t.Fatalf("outcome %d is %+v, want input order", i, o.Item)
+		}
+		if (o.Err != nil) != (i == 7) {
+			t.Fatalf("outcome %d: unexpected error state %v", i, o.Err)
+		}
+		if o.Err == nil && o.Result.InputURL != urls[i] {
+			t.Fatalf("outcome %d: result for %s", i, o.Result.InputURL)
+		}
+	}
+}
+
+func TestScanStream_Unordered(t *testing.T) {
+	s := newScanner(t)
+	urls := []string{"https://a.example.com", "https://bad.example.com", "https://c.example.com"}
+	seen := map[string]bool{}
+	err := s.ScanStream(context.Background(), sliceSource(urls), scan.StreamOptions{Workers: 4}, func(o scan.Outcome) error {
+		seen[o.Result.InputURL] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanStream() error = %v", err)
+	}
+	for _, u := range urls {
+		if !seen[u] {
+			t.Errorf("missing outcome for %s", u)
+		}
+	}
+}
+
+func TestScanStream_Errors(t *testing.T) {
+	s := newScanner(t)
+	urls := make([]string, 1000)
+	for i := range urls {
+		urls[i] = "https://example.com/"
+	}
+
+	// emit errors stop the stream and are returned
+	stop := errors.New("write failed")
+	calls := 0
+	err := s.ScanStream(context.Background(), sliceSource(urls), scan.StreamOptions{Workers: 4, Ordered: true}, func(scan.Outcome) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("err=%v calls=%d, want write failed after 1 call", err, calls)
+	}
+
+	// source errors are returned after the items read so far
+	readErr := errors.New("read failed")
+	src := func(yield func(input.Item) error) error {
+		if err := yield(input.Item{URL: "https://example.com", Line: 1}); err != nil {
+			return err
+		}
+		return readErr
+	}
+	calls = 0
+	err = s.ScanStream(context.Background(), src, scan.StreamOptions{Workers: 2}, func(scan.Outcome) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, readErr) || calls != 1 {
+		t.Fatalf("err=%v calls=%d, want read failed after 1 call", err, calls)
+	}
+}
@@ -0,0 +1,146 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/samuraidays/urwarden/internal/input"
+	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/model"
+)
+
+// windowPerWorker bounds how many items each worker may have outstanding
+// (queued, in flight or waiting to be emitted in order). It keeps memory
+// constant regardless of input size.
+const windowPerWorker = 64
+
+// Source produces input items by calling yield for each one, in order.
+// It must stop and return the error when yield fails.
+type Source func(yield func(input.Item) error) error
+
+// StreamOptions controls ScanStream
+type StreamOptions struct {
+	Workers int  // goroutines scoring concurrently; values below 1 mean 1
+	Ordered bool // emit outcomes in input order instead of completion order
+}
+
+// Outcome is the result of scoring one input item. Err holds the
+// normalization error when the URL could not be scored.
+type Outcome struct {
+	Item   input.Item
+	Result model.Result
+	Err    error
+}
+
+// ScanStream scores every item produced by src with a bounded pool of
+// workers sharing this scanner, and calls emit for each outcome. emit is
+// never called concurrently. Invalid URLs are reported through Outcome.Err
+// and do not stop the stream; an error from src or emit, or cancellation of
+// ctx, stops it and is returned.
+func (s *Scanner) ScanStream(ctx context.Context, src Source, opts StreamOptions, emit func(Outcome) error) error {
+	workers := max(opts.Workers, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		seq  int
+		item input.Item
+	}
+	type done struct {
+		seq     int
+		outcome Outcome
+	}
+	jobs := make(chan job, workers)
+	results := make(chan done, workers)
+	window := make(chan struct{}, workers*windowPerWorker)
+
+	// Producer
+	srcErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		seq := 0
+		srcErr <- src(func(it input.Item) error {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- job{seq: seq, item: it}:
+				seq++
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	// Workers
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				itemCtx := ctx
+				if logger.Enabled(logger.LevelDebug) {
+					// Tie the URL's debug entries to its input line
+					itemCtx = logger.With(logger.WithID(ctx), "source", j.item.Source, "line", j.item.Line)
+				}
+				res, err := s.Scan(itemCtx, j.item.URL)
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case results <- done{seq: j.seq, outcome: Outcome{Item: j.item, Result: res, Err: err}}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collector: emits in completion order, or reorders by sequence number
+	var emitErr error
+	send := func(o Outcome) {
+		if emitErr == nil {
+			if emitErr = emit(o); emitErr != nil {
+				cancel()
+			}
+		}
+		<-window
+	}
+	pending := make(map[int]Outcome)
+	next := 0
+	for d := range results {
+		if !opts.Ordered {
+			send(d.outcome)
+			continue
+		}
+		pending[d.seq] = d.outcome
+		for {
+			o, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			send(o)
+		}
+	}
+
+	err := <-srcErr
+	if emitErr != nil {
+		return emitErr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return ctx.Err()
+}
@@ -2,8 +2,8 @@ package score
 
 import (
 	"github.com/samuraidays/urwarden/internal/config"
-	"github.com/samuraidays/urwarden/internal/logger"
 	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/rules"
 )
 
 // This package handles score calculation and label determination.
@@ -12,6 +12,10 @@ import (
 
 // Aggregate calculates the total score from reasons and determines the appropriate label
 //
+// When reasons contain an allowlisted entry, cfg.AllowlistPolicy decides the outcome:
+//   - "benign": the label is forced to benign; the score is kept for reference
+//   - "cap": the score is capped at cfg.AllowlistScoreCap and the label derived from it
+//
 // Returns:
 //   - int: total score (e.g., 80)
 //   - string: label string ("benign" / "suspicious" / "malicious")
@@ -22,16 +26,55 @@ func Aggregate(reasons []model.Reason, cfg *config.Config) (int, string) {
 	// Example: [{rule:blocklist_hit, weight:70}, {rule:path_has_login_like, weight:10}]
 	for _, r := range reasons {
 		total += r.Weight
-		logger.Debug("added %d points for rule %s: %s", r.Weight, r.Rule, r.Detail)
 	}
 
 	// Determine label based on score thresholds
 	label := labelOf(total, cfg)
 
-	logger.Debug("total score: %d, label: %s", total, label)
+	// Allowlisted hosts override heuristic and blocklist hits
+	if allowlisted(reasons) {
+		switch cfg.AllowlistPolicy {
+		case config.AllowlistCapScore:
+			limit := cfg.AllowlistScoreCap
+			if limit <= 0 {
+				limit = cfg.SuspiciousThreshold - 1
+			}
+			if total > limit {
+				total = limit
+			}
+			label = labelOf(total, cfg)
+		default:
+			label = "benign"
+		}
+	}
+
 	return total, label
 }
 
+// allowlisted reports whether any reason comes from the allowlist rule
+func allowlisted(reasons []model.Reason) bool {
+	for _, r := range reasons {
+		if r.Rule == rules.RuleAllowlisted {
+			return true
+		}
+	}
+	return false
+}
+
+// labelRank orders labels by severity; unknown labels rank lowest
+var labelRank = map[string]int{
+	"benign":     1,
+	"suspicious": 2,
+	"malicious":  3,
+}
+
+// AtLeast reports whether label is as severe as min or more,
+// e.g. AtLeast("malicious", "suspicious") is true
+func AtLeast(label, min string) bool {
+	r, ok := labelRank[label]
+	return ok && r >= labelRank[min]
+}
+
 // labelOf determines the appropriate label based on the score and configuration
 //
 // Score thresholds:
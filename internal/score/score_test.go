@@ -31,3 +31,53 @@ func scoreLabel(total int, cfg *config.Config) string {
 	_, label := score.Aggregate([]model.Reason{{Weight: total}}, cfg)
 	return label
 }
+
+func TestAllowlistPolicy(t *testing.T) {
+	reasons := []model.Reason{
+		{Rule: "blocklist_hit", Weight: 70},
+		{Rule: "path_has_login_like", Weight: 10},
+		{Rule: "allowlisted", Weight: 0, Detail: "ourcorp.com"},
+	}
+
+	cfg := config.Default()
+	total, label := score.Aggregate(reasons, cfg)
+	if total != 80 || label != "benign" {
+		t.Errorf("benign policy: got %d %s, want 80 benign", total, label)
+	}
+
+	cfg.AllowlistPolicy = config.AllowlistCapScore
+	total, label = score.Aggregate(reasons, cfg)
+	if total != 29 || label != "benign" {
+		t.Errorf("cap policy (default cap): got %d %s, want 29 benign", total, label)
+	}
+
+	cfg.AllowlistScoreCap = 50
+	total, label = score.Aggregate(reasons, cfg)
+	if total != 50 || label != "suspicious" {
+		t.Errorf("cap policy (cap 50): got %d %s, want 50 suspicious", total, label)
+	}
+
+	total, label = score.Aggregate(reasons[:2], cfg)
+	if total != 80 || label != "malicious" {
+		t.Errorf("no allowlist hit: got %d %s, want 80 malicious", total, label)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	cases := []struct {
+		label, min string
+		want       bool
+	}{
+		{"malicious", "suspicious", true},
+		{"malicious", "malicious", true},
+		{"suspicious", "suspicious", true},
+		{"suspicious", "malicious", false},
+		{"benign", "suspicious", false},
+		{"unknown", "suspicious", false},
+	}
+	for _, c := range cases {
+		if got := score.AtLeast(c.label, c.min); got != c.want {
+			t.Errorf("AtLeast(%q, %q) = %v, want %v", c.label, c.min, got, c.want)
+		}
+	}
+}
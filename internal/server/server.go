@@ -0,0 +1,235 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/logger"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/output"
+	"github.com/samuraidays/urwarden/internal/scan"
+)
+
+// This package implements the HTTP API behind `urwarden serve`.
+// The scanner (and therefore the blocklist) is loaded once at startup and
+// shared by all requests.
+
+// ScoreRequest is the body of POST /v1/score.
+// Exactly one of URL (single) or URLs (batch) must be set.
+type ScoreRequest struct {
+	URL  string   `json:"url,omitempty"`
+	URLs []string `json:"urls,omitempty"`
+}
+
+// BatchResponse is returned for batch requests.
+// Results keep the request order; URLs that could not be scored are listed in Errors.
+// Filtered counts results left out by only_labels or min_score.
+type BatchResponse struct {
+	Results  []model.Result `json:"results"`
+	Filtered int            `json:"filtered,omitempty"`
+	Errors   []ItemError    `json:"errors,omitempty"`
+}
+
+// FilteredResponse is returned for a single URL that was scored but left
+// out by only_labels or min_score
+type FilteredResponse struct {
+	InputURL string `json:"input_url"`
+	Score    int    `json:"score"`
+	Label    string `json:"label"`
+	Filtered bool   `json:"filtered"` // always true
+}
+
+// ItemError describes a URL in a batch that could not be scored
+type ItemError struct {
+	Index int    `json:"index"`
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// errorResponse is the body of every non-2xx response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves scoring requests
+type Server struct {
+	scanner *scan.Scanner
+	config  *config.Config
+	ready   atomic.Bool
+}
+
+// New creates a server around an initialized scanner.
+// The server reports ready until SetReady(false) is called.
+func New(scanner *scan.Scanner, cfg *config.Config) *Server {
+	s := &Server{
+		scanner: scanner,
+		config:  cfg,
+	}
+	s.ready.Store(true)
+	return s
+}
+
+// SetReady toggles the readiness endpoint, e.g. while shutting down
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Handler returns the HTTP routes:
+//
+//	POST /v1/score - score one URL or a batch
+//	GET  /healthz  - liveness (always 200 while the process runs)
+//	GET  /readyz   - readiness (503 while shutting down)
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/score", s.handleScore)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	return mux
+}
+
+func (s *Server) handleScore(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxRequestBytes)
+
+	var req ScoreRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return
+	}
+
+	// ?defang=true|false overrides the configured default
+	opts := output.Options{Defang: s.config.DefangOutput}
+	if v := r.URL.Query().Get("defang"); v != "" {
+		defang, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid defang parameter %q", v))
+			return
+		}
+		opts.Defang = defang
+	}
+
+	// ?only_labels=a,b and ?min_score=N override the configured filter
+	filter, err := s.filter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case req.URL != "" && req.URLs != nil:
+		writeError(w, http.StatusBadRequest, errors.New(`set either "url" or "urls", not both`))
+	case req.URL != "":
+		s.scoreSingle(w, r, req.URL, filter, opts)
+	case req.URLs != nil:
+		s.scoreBatch(w, r, req.URLs, filter, opts)
+	default:
+		writeError(w, http.StatusBadRequest, errors.New(`missing "url" or "urls"`))
+	}
+}
+
+// filter returns the scanner's result filter with query overrides applied
+func (s *Server) filter(r *http.Request) (scan.Filter, error) {
+	filter := s.scanner.Filter()
+	q := r.URL.Query()
+	if q.Has("only_labels") {
+		filter.Labels = nil
+		for l := range strings.SplitSeq(q.Get("only_labels"), ",") {
+			if l = strings.TrimSpace(l); l == "" {
+				continue
+			}
+			if !slices.Contains(config.Labels, l) {
+				return filter, fmt.Errorf("invalid only_labels parameter: label %q must be one of %s", l, strings.Join(config.Labels, ", "))
+			}
+			filter.Labels = append(filter.Labels, l)
+		}
+	}
+	if v := q.Get("min_score"); v != "" {
+		minScore, err := strconv.Atoi(v)
+		if err != nil || minScore < 0 {
+			return filter, fmt.Errorf("invalid min_score parameter %q", v)
+		}
+		filter.MinScore = minScore
+	}
+	return filter, nil
+}
+
+func (s *Server) scoreSingle(w http.ResponseWriter, r *http.Request, rawURL string, filter scan.Filter, opts output.Options) {
+	res, err := s.scanner.Scan(r.Context(), rawURL)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	res = opts.Prepare(res)
+	if !filter.Keep(res) {
+		// Scored fine, but not worth reporting; say so rather than answering
+		// with an empty body clients may mistake for a failure
+		writeJSON(w, http.StatusOK, FilteredResponse{InputURL: res.InputURL, Score: res.Score, Label: res.Label, Filtered: true})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) scoreBatch(w http.ResponseWriter, r *http.Request, urls []string, filter scan.Filter, opts output.Options) {
+	if len(urls) > s.config.MaxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("batch of %d URLs exceeds limit of %d", len(urls), s.config.MaxBatchSize))
+		return
+	}
+
+	resp := BatchResponse{Results: make([]model.Result, 0, len(urls))}
+	for i, rawURL := range urls {
+		res, err := s.scanner.Scan(r.Context(), rawURL)
+		if err != nil {
+			if r.Context().Err() != nil {
+				// Client went away; nobody is left to read the response
+				return
+			}
+			resp.Errors = append(resp.Errors, ItemError{Index: i, URL: rawURL, Error: err.Error()})
+			continue
+		}
+		if !filter.Keep(res) {
+			resp.Filtered++
+			continue
+		}
+		resp.Results = append(resp.Results, opts.Prepare(res))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if !s.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		logger.Debug("failed to write response", "error", err)
+	}
+}
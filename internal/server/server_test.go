@@ -0,0 +1,183 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/scan"
+	"github.com/samuraidays/urwarden/internal/server"
+)
+
+func newTestServer(t *testing.T) (*server.Server, *httptest.Server) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(p, []byte("bad.example.com\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	cfg := config.Default()
+	cfg.BlocklistPath = p
+	cfg.MaxBatchSize = 3
+	scanner, err := scan.New(cfg)
+	if err != nil {
+		t.Fatalf("scan.New() error = %v", err)
+	}
+	srv := server.New(scanner, cfg)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func post(t *testing.T, ts *httptest.Server, body string) *http.Response {
+	t.Helper()
+	return postTo(t, ts, "/v1/score", body)
+}
+
+func postTo(t *testing.T, ts *httptest.Server, path, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestScoreSingle(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp := post(t, ts, `{"url":"https://bad.example.com/login"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var res model.Result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.Label != "malicious" || res.Score != 80 {
+		t.Fatalf("got score=%d label=%s", res.Score, res.Label)
+	}
+}
+
+func TestScoreDefang(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp := postTo(t, ts, "/v1/score?defang=true", `{"urls":["https://bad.example.com/login","https://example.com/"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var batch server.BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := batch.Results[0].InputURL; got != "hxxps://bad[.]example[.]com/login" {
+		t.Errorf("malicious input_url = %s, want defanged", got)
+	}
+	if got := batch.Results[1].InputURL; got != "https://example.com/" {
+		t.Errorf("benign input_url = %s, want unchanged", got)
+	}
+
+	if resp := postTo(t, ts, "/v1/score?defang=maybe", `{"url":"https://example.com"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid defang parameter: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestScoreFilter(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp := postTo(t, ts, "/v1/score?only_labels=malicious,suspicious", `{"urls":["https://bad.example.com/login","https://example.com/"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var batch server.BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(batch.Results) != 1 || batch.Results[0].Label != "malicious" || batch.Filtered != 1 {
+		t.Errorf("got %d results, %d filtered; want the malicious one and 1 filtered", len(batch.Results), batch.Filtered)
+	}
+
+	resp = postTo(t, ts, "/v1/score?min_score=50", `{"url":"https://example.com/"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("filtered single URL: status = %d, want 200", resp.StatusCode)
+	}
+	var filtered server.FilteredResponse
+	if err := json.NewDecoder(resp.Body).Decode(&filtered); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !filtered.Filtered || filtered.InputURL != "https://example.com/" || filtered.Label != "benign" {
+		t.Errorf("filtered single URL = %+v", filtered)
+	}
+	for _, q := range []string{"only_labels=bad", "min_score=-1", "min_score=x"} {
+		if resp := postTo(t, ts, "/v1/score?"+q, `{"url":"https://example.com"}`); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, resp.StatusCode)
+		}
+	}
+}
+
+func TestScoreBatch(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp := post(t, ts, `{"urls":["https://bad.example.com","ftp://nope","https://example.com"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var br server.BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(br.Results) != 2 || br.Results[0].InputURL != "https://bad.example.com" {
+		t.Fatalf("unexpected results: %+v", br.Results)
+	}
+	if len(br.Errors) != 1 || br.Errors[0].Index != 1 {
+		t.Fatalf("unexpected errors: %+v", br.Errors)
+	}
+}
+
+func TestScoreErrors(t *testing.T) {
+	_, ts := newTestServer(t)
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`not json`, http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+		{`{"url":"https://a.example","urls":["https://b.example"]}`, http.StatusBadRequest},
+		{`{"unknown":1}`, http.StatusBadRequest},
+		{`{"url":"ftp://example.com"}`, http.StatusUnprocessableEntity},
+		{`{"urls":["https://a","https://b","https://c","https://d"]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		if resp := post(t, ts, c.body); resp.StatusCode != c.want {
+			t.Errorf("body %s: status = %d, want %d", c.body, resp.StatusCode, c.want)
+		}
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	srv, ts := newTestServer(t)
+	get := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Fatalf("healthz = %d", got)
+	}
+	if got := get("/readyz"); got != http.StatusOK {
+		t.Fatalf("readyz = %d", got)
+	}
+	srv.SetReady(false)
+	if got := get("/readyz"); got != http.StatusServiceUnavailable {
+		t.Fatalf("readyz after SetReady(false) = %d", got)
+	}
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Fatalf("healthz after SetReady(false) = %d", got)
+	}
+}
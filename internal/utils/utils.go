@@ -2,6 +2,8 @@ package utils
 
 import (
 	"strings"
+
+	"github.com/samuraidays/urwarden/internal/idn"
 )
 
 // Dedupe removes duplicate strings from a slice while preserving order
@@ -47,6 +49,15 @@ func NormalizeDomain(domain string) string {
 		return ""
 	}
 
+	// IDN entries are stored in ASCII form to match normalized hosts
+	if !idn.IsASCII(domain) {
+		ascii, err := idn.ToASCII(domain)
+		if err != nil {
+			return ""
+		}
+		domain = strings.ToLower(ascii)
+	}
+
 	return domain
 }
 
@@ -0,0 +1,231 @@
+// Package urwarden scores URLs for phishing and malware risk.
+//
+// It exposes the same pipeline the urwarden CLI runs: URL normalization,
+// blocklist and heuristic rule evaluation, and score aggregation into a
+// benign / suspicious / malicious label.
+//
+//	s, err := urwarden.New(urwarden.WithBlocklist("data/blocklist.txt"))
+//	if err != nil {
+//		return err
+//	}
+//	res, err := s.Scan(ctx, "https://bad.example.com/login")
+package urwarden
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/samuraidays/urwarden/internal/config"
+	"github.com/samuraidays/urwarden/internal/model"
+	"github.com/samuraidays/urwarden/internal/parse"
+	"github.com/samuraidays/urwarden/internal/rules"
+	"github.com/samuraidays/urwarden/internal/scan"
+)
+
+// ResultSchemaVersion identifies the layout of Result and its JSON encoding.
+// It is bumped when a field is removed or changes meaning; adding optional
+// fields does not bump it.
+const ResultSchemaVersion = 1
+
+// Result types returned by Scan. They are the same documents the CLI prints.
+type (
+	Result        = model.Result
+	NormalizedURL = model.NormalizedURL
+	Reason        = model.Reason
+)
+
+// Labels assigned to a Result
+const (
+	LabelBenign     = "benign"
+	LabelSuspicious = "suspicious"
+	LabelMalicious  = "malicious"
+)
+
+// Errors returned by Scan for URLs that cannot be scored
+var (
+	ErrInvalidScheme = parse.ErrInvalidScheme
+	ErrNoHost        = parse.ErrNoHost
+	ErrInvalidHost   = parse.ErrInvalidHost
+)
+
+// Rule is a custom detection check. See WithRule.
+//
+// Evaluate must be safe for concurrent use and should return one Reason per
+// finding, normally using Name() as Reason.Rule and DefaultWeight() as
+// Reason.Weight.
+type Rule = rules.Rule
+
+// Names of the built-in rules, in evaluation order
+const (
+	RuleBlocklistHit     = rules.RuleBlocklistHit
+	RuleSuspiciousTLD    = rules.RuleSuspiciousTLD
+	RulePathHasLoginLike = rules.RulePathHasLoginLike
+	RuleIDNHomograph     = rules.RuleIDNHomograph
+	RuleAllowlisted      = rules.RuleAllowlisted
+)
+
+// Option configures a Scanner
+type Option func(*options)
+
+// options collects everything New needs before building the pipeline
+type options struct {
+	config *config.Config
+	rules  []Rule
+}
+
+// WithBlocklist sets the blocklist file (default: data/blocklist.txt).
+// A missing file is treated as an empty blocklist.
+func WithBlocklist(path string) Option {
+	return func(o *options) {
+		o.config.BlocklistPath = path
+	}
+}
+
+// Blocklist describes a named blocklist for WithBlocklists
+type Blocklist struct {
+	Name     string // reported in Reason.List; defaults to the file name without extension
+	Path     string
+	Category string // reported in Reason.Category, e.g. phishing | malware | ads
+	Weight   int    // score points for a hit; 0 uses the blocklist_hit default (70)
+}
+
+// WithBlocklists loads several named blocklists instead of the single
+// WithBlocklist file. Every list that matches a host adds its own
+// blocklist_hit reason with the list's weight.
+func WithBlocklists(lists ...Blocklist) Option {
+	return func(o *options) {
+		for _, l := range lists {
+			name := l.Name
+			if name == "" {
+				base := filepath.Base(l.Path)
+				name = strings.TrimSuffix(base, filepath.Ext(base))
+			}
+			o.config.Blocklists = append(o.config.Blocklists, config.BlocklistSpec{
+				Name:     name,
+				Path:     l.Path,
+				Category: l.Category,
+				Weight:   l.Weight,
+			})
+		}
+	}
+}
+
+// WithVerifiedBlocklists makes New refuse blocklists that fail a check: with
+// checksum, <list>.sha256 must match; with publicKeys (minisign keys or .pub
+// files), <list>.minisig must be a valid signature by one of them.
+func WithVerifiedBlocklists(checksum bool, publicKeys ...string) Option {
+	return func(o *options) {
+		o.config.VerifyChecksums = checksum
+		o.config.ListPublicKeys = publicKeys
+	}
+}
+
+// WithAllowlist sets an allowlist file (blocklist format). Hosts matching an
+// entry, or a subdomain of one, are labelled benign regardless of other hits.
+// New fails when the file does not exist.
+func WithAllowlist(path string) Option {
+	return func(o *options) {
+		o.config.AllowlistPath = path
+	}
+}
+
+// WithAllowlistScoreCap switches the allowlist policy from forcing the benign
+// label to capping the score at limit (0: just below the suspicious threshold)
+func WithAllowlistScoreCap(limit int) Option {
+	return func(o *options) {
+		o.config.AllowlistPolicy = config.AllowlistCapScore
+		o.config.AllowlistScoreCap = limit
+	}
+}
+
+// WithPublicSuffixList replaces the embedded Public Suffix List with the list
+// at path (publicsuffix.org format) for this Scanner only.
+func WithPublicSuffixList(path string) Option {
+	return func(o *options) {
+		o.config.PublicSuffixListPath = path
+	}
+}
+
+// WithThresholds sets the minimum scores for the suspicious and malicious labels
+func WithThresholds(suspicious, malicious int) Option {
+	return func(o *options) {
+		o.config.SuspiciousThreshold = suspicious
+		o.config.MaliciousThreshold = malicious
+	}
+}
+
+// WithProtectedDomains replaces the brand domains whose IDN look-alikes are
+// flagged by the idn_homograph rule
+func WithProtectedDomains(domains ...string) Option {
+	return func(o *options) {
+		o.config.ProtectedDomains = domains
+	}
+}
+
+// WithRule registers a custom rule. Custom rules run after the built-in
+// ones, in the order they are given. Names must be unique.
+func WithRule(rule Rule) Option {
+	return func(o *options) {
+		o.rules = append(o.rules, rule)
+	}
+}
+
+// WithDisabledRules skips the named rules (built-in or custom)
+func WithDisabledRules(names ...string) Option {
+	return func(o *options) {
+		o.config.DisabledRules = append(o.config.DisabledRules, names...)
+	}
+}
+
+// WithOnlyLabels makes Keep accept only results with one of labels
+func WithOnlyLabels(labels ...string) Option {
+	return func(o *options) {
+		o.config.OnlyLabels = labels
+	}
+}
+
+// WithMinScore makes Keep accept only results scoring at least score
+func WithMinScore(score int) Option {
+	return func(o *options) {
+		o.config.MinScore = score
+	}
+}
+
+// Scanner scores URLs. It is safe for concurrent use; create one and share it.
+type Scanner struct {
+	scanner *scan.Scanner
+}
+
+// New creates a Scanner with the CLI defaults, modified by opts.
+// The blocklist is loaded once here.
+func New(opts ...Option) (*Scanner, error) {
+	o := &options{config: config.Default()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s, err := scan.New(o.config)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range o.rules {
+		if err := s.Evaluator().Register(rule); err != nil {
+			return nil, err
+		}
+	}
+	return &Scanner{scanner: s}, nil
+}
+
+// Scan normalizes, evaluates and scores rawURL.
+// It returns ErrInvalidScheme or ErrNoHost (or a *url.Error) for URLs that
+// cannot be scored, and ctx.Err() if ctx is already done.
+func (s *Scanner) Scan(ctx context.Context, rawURL string) (Result, error) {
+	return s.scanner.Scan(ctx, rawURL)
+}
+
+// Keep reports whether res passes the WithOnlyLabels and WithMinScore
+// filters. Scan returns every result; callers skip the ones Keep rejects.
+func (s *Scanner) Keep(res Result) bool {
+	return s.scanner.Filter().Keep(res)
+}